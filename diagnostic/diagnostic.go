@@ -0,0 +1,79 @@
+// Package diagnostic reports problems found in Monkey source code in a structured
+// form suitable for editors and other tooling.
+package diagnostic
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/magalhaesm/monkey-lang/lexer"
+	"github.com/magalhaesm/monkey-lang/token"
+)
+
+// Severity levels of a diagnostic.
+const (
+	SeverityError = "error"
+)
+
+// Diagnostic describes a problem in the source, located by 1-based line and
+// columns. EndColumn is the column just past the end of the problem; problems that
+// span several lines end with their first line.
+type Diagnostic struct {
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndColumn int    `json:"endColumn"`
+	Severity  string `json:"severity"`
+	Message   string `json:"message"`
+}
+
+// Collect returns the diagnostics for all lexical errors in src, in source order,
+// including those inside the expressions embedded in template strings.
+func Collect(src string) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	l := lexer.New(src)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		diagnostics = appendDiagnostics(diagnostics, l, tok)
+	}
+
+	return diagnostics
+}
+
+// appendDiagnostics appends the diagnostics for tok, and any tokens embedded in it,
+// to diagnostics. The lexer l is used to look up source lines.
+func appendDiagnostics(diagnostics []Diagnostic, l *lexer.Lexer, tok token.Token) []Diagnostic {
+	var message string
+	switch tok.Type {
+	case token.ILLEGAL:
+		message = fmt.Sprintf("illegal character %q", tok.Literal)
+	case token.ERROR:
+		message = tok.Literal
+	case token.TEMPLATE:
+		for _, part := range tok.Parts {
+			for _, embedded := range part.Expr {
+				diagnostics = appendDiagnostics(diagnostics, l, embedded)
+			}
+		}
+		return diagnostics
+	default:
+		return diagnostics
+	}
+
+	endColumn := tok.End.Column
+	if tok.End.Line != tok.Pos.Line {
+		endColumn = len(l.Line(tok.Pos.Line)) + 1
+	}
+
+	return append(diagnostics, Diagnostic{
+		Line:      tok.Pos.Line,
+		Column:    tok.Pos.Column,
+		EndColumn: endColumn,
+		Severity:  SeverityError,
+		Message:   message,
+	})
+}
+
+// JSON returns the diagnostics for src encoded as a JSON array.
+func JSON(src string) ([]byte, error) {
+	return json.Marshal(Collect(src))
+}
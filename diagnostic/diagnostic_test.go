@@ -0,0 +1,39 @@
+package diagnostic
+
+import "testing"
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let x = 5;",
+			`[]`,
+		},
+		{
+			"let x = 5 @ 2;\nlet s = \"unterminated\nstring",
+			`[{"line":1,"column":11,"endColumn":12,"severity":"error","message":"illegal character \"@\""},` +
+				`{"line":2,"column":9,"endColumn":22,"severity":"error","message":"unterminated string"}]`,
+		},
+		{
+			`let s = "a ${b @ c}";`,
+			`[{"line":1,"column":16,"endColumn":17,"severity":"error","message":"illegal character \"@\""}]`,
+		},
+		{
+			`let s = "\q";`,
+			`[{"line":1,"column":9,"endColumn":13,"severity":"error","message":"invalid escape sequence \"\\\\q\""}]`,
+		},
+	}
+
+	for i, tt := range tests {
+		got, err := JSON(tt.input)
+		if err != nil {
+			t.Fatalf("tests[%d] - JSON returned error: %v", i, err)
+		}
+
+		if string(got) != tt.expected {
+			t.Errorf("tests[%d] - JSON wrong.\nexpected: %s\ngot:      %s", i, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package highlight renders Monkey source code with syntax highlighting.
+package highlight
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/magalhaesm/monkey-lang/lexer"
+	"github.com/magalhaesm/monkey-lang/token"
+)
+
+// category classifies tokens for highlighting purposes.
+type category string
+
+// Token categories. Tokens that fall in none of them, like delimiters, are left plain.
+const (
+	plain      category = ""
+	keyword    category = "keyword"
+	number     category = "number"
+	str        category = "string"
+	operator   category = "operator"
+	identifier category = "identifier"
+)
+
+// ansiColors maps each highlighted category to its ANSI color escape code.
+var ansiColors = map[category]string{
+	keyword:    "\x1b[35m", // Magenta
+	number:     "\x1b[36m", // Cyan
+	str:        "\x1b[32m", // Green
+	operator:   "\x1b[33m", // Yellow
+	identifier: "\x1b[34m", // Blue
+}
+
+const ansiReset = "\x1b[0m"
+
+// ANSI returns src with its keywords, numbers, strings, operators and identifiers
+// wrapped in ANSI color codes. Everything else, including whitespace and comments,
+// is passed through unchanged, so stripping the color codes yields src.
+func ANSI(src string) string {
+	return render(src, func(out *strings.Builder, c category, text string) {
+		if color, ok := ansiColors[c]; ok {
+			out.WriteString(color + text + ansiReset)
+		} else {
+			out.WriteString(text)
+		}
+	})
+}
+
+// HTML returns src HTML-escaped, with its keywords, numbers, strings, operators and
+// identifiers wrapped in <span> elements whose class names their category, e.g.
+// <span class="keyword">let</span>. Stripping the tags and unescaping the result
+// yields src. It returns an error if src is not valid UTF-8.
+func HTML(src string) (string, error) {
+	if !utf8.ValidString(src) {
+		return "", errors.New("highlight: source is not valid UTF-8")
+	}
+	return render(src, func(out *strings.Builder, c category, text string) {
+		if c == plain {
+			out.WriteString(html.EscapeString(text))
+			return
+		}
+		out.WriteString(`<span class="` + string(c) + `">`)
+		out.WriteString(html.EscapeString(text))
+		out.WriteString("</span>")
+	}), nil
+}
+
+// render lexes src and passes each piece of it in order to write: the text of every
+// token along with its category, and the text between tokens as plain.
+func render(src string, write func(out *strings.Builder, c category, text string)) string {
+	var out strings.Builder
+
+	offset := 0
+	l := lexer.New(src)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if gap := src[offset:tok.Pos.Offset]; gap != "" {
+			write(&out, plain, gap)
+		}
+		write(&out, categorize(tok.Type), src[tok.Pos.Offset:tok.End.Offset])
+		offset = tok.End.Offset
+	}
+	if rest := src[offset:]; rest != "" {
+		write(&out, plain, rest)
+	}
+
+	return out.String()
+}
+
+// categorize returns the highlighting category of the token type.
+func categorize(t token.TokenType) category {
+	switch {
+	case token.IsKeyword(t):
+		return keyword
+	case token.IsOperator(t):
+		return operator
+	case t == token.INT:
+		return number
+	case t == token.STRING, t == token.TEMPLATE:
+		return str
+	case t == token.IDENT:
+		return identifier
+	default:
+		return plain
+	}
+}
@@ -0,0 +1,86 @@
+package highlight
+
+import (
+	"html"
+	"regexp"
+	"testing"
+)
+
+var ansiCode = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func TestANSIPreservesSource(t *testing.T) {
+	tests := []string{
+		"",
+		"let five = 5;",
+		"let add = fn(x, y) {\n\tx + y; // sum\n};\n",
+		"if (5 < 10) { return true; } else { return false; }",
+		"let s = \"a\\tb\\u{e9}\"; s != \"\";",
+		"// only a comment",
+		"let bad = @ \"unterminated",
+		"  \r\n  10 == 10  \n",
+	}
+
+	for i, src := range tests {
+		got := ansiCode.ReplaceAllString(ANSI(src), "")
+		if got != src {
+			t.Errorf("tests[%d] - stripped output wrong. expected %q, got %q", i, src, got)
+		}
+	}
+}
+
+func TestANSIColors(t *testing.T) {
+	src := `let x = "hi" + 10; // note`
+	expected := "\x1b[35mlet\x1b[0m \x1b[34mx\x1b[0m \x1b[33m=\x1b[0m " +
+		"\x1b[32m\"hi\"\x1b[0m \x1b[33m+\x1b[0m \x1b[36m10\x1b[0m; // note"
+
+	if got := ANSI(src); got != expected {
+		t.Errorf("ANSI output wrong.\nexpected: %q\ngot:      %q", expected, got)
+	}
+}
+
+var htmlTag = regexp.MustCompile("<[^>]*>")
+
+func TestHTML(t *testing.T) {
+	src := `let s = "<b>" + "&'"; if (a < b) { s } // a > b`
+	expected := `<span class="keyword">let</span> <span class="identifier">s</span> ` +
+		`<span class="operator">=</span> <span class="string">&#34;&lt;b&gt;&#34;</span> ` +
+		`<span class="operator">+</span> <span class="string">&#34;&amp;&#39;&#34;</span>; ` +
+		`<span class="keyword">if</span> (<span class="identifier">a</span> ` +
+		`<span class="operator">&lt;</span> <span class="identifier">b</span>) ` +
+		`{ <span class="identifier">s</span> } // a &gt; b`
+
+	got, err := HTML(src)
+	if err != nil {
+		t.Fatalf("HTML returned error: %v", err)
+	}
+
+	if got != expected {
+		t.Errorf("HTML output wrong.\nexpected: %q\ngot:      %q", expected, got)
+	}
+}
+
+func TestHTMLPreservesSource(t *testing.T) {
+	tests := []string{
+		"",
+		"let add = fn(x, y) {\n\tx + y; // sum\n};\n",
+		"5 < 10 > 5 && \"a\" != \"b\"",
+		"let bad = @ \"unterminated <",
+	}
+
+	for i, src := range tests {
+		got, err := HTML(src)
+		if err != nil {
+			t.Fatalf("tests[%d] - HTML returned error: %v", i, err)
+		}
+
+		if stripped := html.UnescapeString(htmlTag.ReplaceAllString(got, "")); stripped != src {
+			t.Errorf("tests[%d] - stripped output wrong. expected %q, got %q", i, src, stripped)
+		}
+	}
+}
+
+func TestHTMLInvalidUTF8(t *testing.T) {
+	if _, err := HTML("let x = \"\xff\";"); err == nil {
+		t.Fatalf("expected an error for invalid UTF-8 source")
+	}
+}
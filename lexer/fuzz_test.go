@@ -0,0 +1,91 @@
+package lexer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/magalhaesm/monkey-lang/token"
+)
+
+func FuzzLexer(f *testing.F) {
+	seeds := []string{
+		"",
+		"let x = 5;",
+		"fn(x, y) { x + y; }",
+		"!-/*5; 5 < 10 > 5; 10 == 10; 10 != 9;",
+		"// comment without newline",
+		"/",
+		"\\",
+		"\"unterminated",
+		"\"\\x41\\u00e9\\u{1F600}\"",
+		"\"\\",
+		"\"${ {\"a\": \"${b}\"} }\"",
+		"\"${",
+		"99999999999999999999999999999999",
+		"\xff\xfe\x00",
+		"a\x00b",
+		"é = 1",
+		"\r\n\t ",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		for _, opts := range [][]Option{nil, {WithComments()}} {
+			l := New(input, opts...)
+
+			// Every token other than EOF consumes at least one byte, so the
+			// lexer must reach EOF within len(input)+1 tokens.
+			offset := -1
+			for i := 0; ; i++ {
+				if i > len(input) {
+					t.Fatalf("lexer did not reach EOF after %d tokens", i)
+				}
+
+				tok := l.NextToken()
+
+				if tok.Pos.Offset <= offset && tok.Type != token.EOF {
+					t.Fatalf("token %d offset %d does not advance past %d", i, tok.Pos.Offset, offset)
+				}
+				if tok.Pos.Offset > len(input) {
+					t.Fatalf("token %d offset %d is past the end of input", i, tok.Pos.Offset)
+				}
+				offset = tok.Pos.Offset
+
+				if tok.Type == token.EOF {
+					if tok.Pos.Offset != len(input) {
+						t.Fatalf("EOF at offset %d before the end of input (%d)", tok.Pos.Offset, len(input))
+					}
+					break
+				}
+			}
+
+			if tok := l.NextToken(); tok.Type != token.EOF || tok.Pos.Offset != len(input) {
+				t.Fatalf("expected EOF at offset %d to repeat, got %q at %d", len(input), tok.Type, tok.Pos.Offset)
+			}
+		}
+	})
+}
+
+func FuzzRelex(f *testing.F) {
+	f.Add("let x = 5;\nlet y = \"s\"; // c\n", 4, 1, "abc")
+	f.Add("fn(x) { x == 1 }", 10, 0, "=")
+	f.Add("a /b", 2, 0, "/")
+	f.Add("..a", 2, 1, ".")
+	f.Add("x\n\"a ${b + \"${c}\"} d\"", 0, 1, "\n\n")
+	f.Add("\"abc\" + 1", 0, 1, "")
+
+	f.Fuzz(func(t *testing.T, source string, offset, removed int, inserted string) {
+		if offset < 0 || removed < 0 || offset > len(source) || removed > len(source)-offset {
+			t.Skip()
+		}
+
+		edit := Edit{Offset: offset, Removed: removed, Inserted: inserted}
+		src := source[:offset] + inserted + source[offset+removed:]
+
+		if got, want := Relex(src, Tokenize(source), edit), Tokenize(src); !reflect.DeepEqual(got, want) {
+			t.Fatalf("incremental tokens differ from a full re-lex.\nexpected: %+v\ngot:      %+v", want, got)
+		}
+	})
+}
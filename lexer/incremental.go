@@ -0,0 +1,141 @@
+package lexer
+
+import "github.com/magalhaesm/monkey-lang/token"
+
+// Edit describes a change to a source text: Removed bytes starting at Offset are
+// replaced by Inserted.
+type Edit struct {
+	Offset   int    // Byte offset in the original source where the edit starts
+	Removed  int    // Number of bytes removed from the original source
+	Inserted string // Text inserted in their place
+}
+
+// Tokenize lexes the whole input and returns its tokens, ending with the EOF token.
+func Tokenize(input string, opts ...Option) []token.Token {
+	var tokens []token.Token
+
+	l := New(input, opts...)
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
+}
+
+// Relex returns the tokens of src, the source after applying edit, given prev, the
+// tokens of the source before the edit as returned by Tokenize without options.
+// Only the region affected by the edit is lexed again; the tokens around it are
+// reused from prev, with their positions adjusted after the edit.
+//
+// The boundaries of the re-lexed region are:
+//
+//   - Before the edit, a token is reused only if it ends before edit.Offset. To find
+//     where a token ends, the lexer reads up to the character at its End, so a token
+//     ending exactly at the edit may change (e.g. inserting "bar" after "foo").
+//     Lexing restarts at the end of the last reused token, so changes to the
+//     whitespace or comments preceding the edit are also picked up.
+//   - After the edit, lexing continues until it produces a token starting at the
+//     same place, relative to the end of the edit, as a token in prev. Since lexing
+//     from a token start depends only on the text that follows it, the remaining
+//     tokens from prev are then reused. An edit that opens a string or comment can
+//     therefore extend the re-lexed region up to the end of the source.
+func Relex(src string, prev []token.Token, edit Edit) []token.Token {
+	keep := 0
+	for keep < len(prev) && prev[keep].End.Offset < edit.Offset {
+		keep++
+	}
+	tokens := append([]token.Token(nil), prev[:keep]...)
+
+	l := New(src)
+	if keep > 0 {
+		l.resumeAt(prev[keep-1].End)
+	}
+
+	delta := len(edit.Inserted) - edit.Removed
+	editEnd := edit.Offset + len(edit.Inserted)
+
+	old := keep
+	for {
+		tok := l.NextToken()
+
+		if tok.Pos.Offset >= editEnd {
+			for old < len(prev) && prev[old].Pos.Offset+delta < tok.Pos.Offset {
+				old++
+			}
+			if old < len(prev) && prev[old].Pos.Offset+delta == tok.Pos.Offset {
+				return append(tokens, shiftTokens(prev[old:], prev[old].Pos, tok.Pos)...)
+			}
+		}
+
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
+}
+
+// resumeAt moves the lexer to the given position, as if it had just finished
+// scanning a token ending there.
+func (l *Lexer) resumeAt(pos token.Position) {
+	l.position = pos.Offset
+	l.readPosition = pos.Offset + 1
+	l.line = pos.Line
+	l.lineStart = pos.Offset - (pos.Column - 1)
+	if pos.Offset < len(l.input) {
+		l.ch = l.input[pos.Offset]
+	} else {
+		l.ch = 0
+	}
+}
+
+// shiftTokens returns copies of tokens, including those embedded in templates, with
+// their positions moved so that the position from becomes to.
+func shiftTokens(tokens []token.Token, from, to token.Position) []token.Token {
+	shifted := make([]token.Token, len(tokens))
+	for i, tok := range tokens {
+		tok.Pos = shiftPosition(tok.Pos, from, to)
+		tok.End = shiftPosition(tok.End, from, to)
+		if tok.Parts != nil {
+			parts := make([]token.TemplatePart, len(tok.Parts))
+			for j, part := range tok.Parts {
+				if part.Expr != nil {
+					part.Expr = shiftTokens(part.Expr, from, to)
+				}
+				parts[j] = part
+			}
+			tok.Parts = parts
+		}
+		shifted[i] = tok
+	}
+	return shifted
+}
+
+// shiftPosition moves pos by the offset and line between from and to. Columns only
+// change for positions on the same line as from.
+func shiftPosition(pos, from, to token.Position) token.Position {
+	if pos.Line == from.Line {
+		pos.Column += to.Column - from.Column
+	}
+	pos.Offset += to.Offset - from.Offset
+	pos.Line += to.Line - from.Line
+	return pos
+}
+
+// ValidPrefix lexes the input for as long as it is valid. If it reaches the end of the
+// input, it returns all tokens, ending with EOF, and ok is true. Otherwise it stops at
+// the first ILLEGAL or ERROR token and returns the tokens before it together with the
+// position of the bad token, leaving the lexer just past it so that lexing can resume.
+func (l *Lexer) ValidPrefix() (tokens []token.Token, errPos token.Position, ok bool) {
+	for {
+		tok := l.NextToken()
+		switch tok.Type {
+		case token.ILLEGAL, token.ERROR:
+			return tokens, tok.Pos, false
+		case token.EOF:
+			return append(tokens, tok), token.Position{}, true
+		}
+		tokens = append(tokens, tok)
+	}
+}
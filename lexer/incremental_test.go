@@ -0,0 +1,106 @@
+package lexer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/magalhaesm/monkey-lang/token"
+)
+
+func TestRelex(t *testing.T) {
+	source := "let five = 5;\nlet ten = 10; // ten\n\nlet add = fn(x, y) {\n\tx + y;\n};\nlet s = \"str\";\n"
+
+	tests := []struct {
+		name string
+		edit Edit
+	}{
+		{"extend identifier", Edit{Offset: 8, Inserted: "ty"}},
+		{"join tokens", Edit{Offset: 12, Removed: 1}},
+		{"replace number", Edit{Offset: 11, Removed: 1, Inserted: "500"}},
+		{"delete statement", Edit{Offset: 0, Removed: 14}},
+		{"insert newlines", Edit{Offset: 4, Inserted: "\n\n  "}},
+		{"remove newline", Edit{Offset: 13, Removed: 1}},
+		{"edit comment", Edit{Offset: 30, Removed: 3, Inserted: "eleven"}},
+		{"open comment", Edit{Offset: 17, Inserted: "//"}},
+		{"open string", Edit{Offset: 25, Inserted: "\""}},
+		{"close string early", Edit{Offset: 78, Removed: 1, Inserted: "\"x"}},
+		{"create two-char operator", Edit{Offset: 10, Inserted: "="}},
+		{"append at end", Edit{Offset: len(source), Inserted: "puts(s)"}},
+		{"insert at start", Edit{Offset: 0, Inserted: "let z = 0;"}},
+		{"replace everything", Edit{Offset: 0, Removed: len(source), Inserted: "1 + 2"}},
+	}
+
+	for _, tt := range tests {
+		src := source[:tt.edit.Offset] + tt.edit.Inserted + source[tt.edit.Offset+tt.edit.Removed:]
+
+		got := Relex(src, Tokenize(source), tt.edit)
+		want := Tokenize(src)
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s - incremental tokens differ from a full re-lex.\nexpected: %+v\ngot:      %+v", tt.name, want, got)
+		}
+	}
+}
+
+func TestRelexSequence(t *testing.T) {
+	src := "let x = 1;\nlet y = x * 2;\n"
+	tokens := Tokenize(src)
+
+	edits := []Edit{
+		{Offset: 8, Removed: 1, Inserted: "42"},
+		{Offset: 11, Inserted: "// doc\n"},
+		{Offset: 4, Removed: 1, Inserted: "value"},
+		{Offset: 0, Inserted: "\n"},
+	}
+
+	for i, edit := range edits {
+		src = src[:edit.Offset] + edit.Inserted + src[edit.Offset+edit.Removed:]
+		tokens = Relex(src, tokens, edit)
+
+		if want := Tokenize(src); !reflect.DeepEqual(tokens, want) {
+			t.Fatalf("edits[%d] - incremental tokens differ from a full re-lex.\nexpected: %+v\ngot:      %+v", i, want, tokens)
+		}
+	}
+}
+
+func TestValidPrefix(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedTypes  []token.TokenType
+		expectedOffset int
+		expectedOk     bool
+	}{
+		{"let x = 5 @", []token.TokenType{token.LET, token.IDENT, token.ASSIGN, token.INT}, 10, false},
+		{"x + \"open", []token.TokenType{token.IDENT, token.PLUS}, 4, false},
+		{"$", nil, 0, false},
+		{"f(1);", []token.TokenType{token.IDENT, token.LPAREN, token.INT, token.RPAREN, token.SEMICOLON, token.EOF}, 0, true},
+	}
+
+	for i, tt := range tests {
+		tokens, errPos, ok := New(tt.input).ValidPrefix()
+
+		if ok != tt.expectedOk {
+			t.Fatalf("tests[%d] - ok wrong. expected %t, got %t", i, tt.expectedOk, ok)
+		}
+
+		if !ok && errPos.Offset != tt.expectedOffset {
+			t.Fatalf("tests[%d] - error offset wrong. expected %d, got %d", i, tt.expectedOffset, errPos.Offset)
+		}
+
+		if len(tokens) != len(tt.expectedTypes) {
+			t.Fatalf("tests[%d] - wrong number of tokens. expected %d, got %d", i, len(tt.expectedTypes), len(tokens))
+		}
+
+		for j, tok := range tokens {
+			if tok.Type != tt.expectedTypes[j] {
+				t.Fatalf("tests[%d] - tokens[%d] tokentype wrong. expected %q, got %q", i, j, tt.expectedTypes[j], tok.Type)
+			}
+		}
+	}
+
+	l := New("a @ b")
+	l.ValidPrefix()
+	if tok := l.NextToken(); tok.Type != token.IDENT || tok.Literal != "b" {
+		t.Fatalf("expected lexing to resume after the bad token, got %q %q", tok.Type, tok.Literal)
+	}
+}
@@ -1,6 +1,14 @@
 package lexer
 
-import "github.com/magalhaesm/monkey-lang/token"
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/magalhaesm/monkey-lang/token"
+)
 
 // Lexer represents the lexer (or scanner) for tokenizing the input string.
 type Lexer struct {
@@ -8,18 +16,185 @@ type Lexer struct {
 	position     int    // Current position in input (points to current character)
 	readPosition int    // Current reading position in input (points to next character)
 	ch           byte   // Current character under examination
+	line         int    // Line of the current character, starting at 1
+	lineStart    int    // Position in input where the current line starts
+	lineStarts   []int  // Positions in input where each line seen so far starts
+
+	buffer  []token.Token // Tokens already scanned by PeekN but not yet consumed
+	last    token.Token   // Last token scanned
+	pending *token.Token  // Token scanned but held back to insert a semicolon before it
+
+	preserveComments bool                     // Whether comments are attached to tokens instead of discarded
+	hashComments     bool                     // Whether '#' starts a line comment
+	insertSemicolons bool                     // Whether virtual semicolons are inserted
+	intDiv           bool                     // Whether "//" is the integer division operator instead of a comment
+	foldKeywords     bool                     // Whether keywords are matched regardless of case
+	limits           Limits                   // Maximum lengths of literals
+	disallowed       map[token.TokenType]bool // Token types rejected with an ERROR token
+	operators        *trieNode                // Trie of the operators and delimiters to recognize
+}
+
+// Option configures optional behavior of a Lexer.
+type Option func(*Lexer)
+
+// WithComments makes the lexer attach comments to tokens instead of discarding them.
+// Comments on their own lines are attached to the Comments of the token that follows
+// them, while a comment ending the line of a token is attached to its Trailing field.
+func WithComments() Option {
+	return func(l *Lexer) {
+		l.preserveComments = true
+	}
+}
+
+// WithHashComments makes '#' start a line comment, like "//". Since '#' is then
+// always part of a comment, it cannot be used by any operator.
+func WithHashComments() Option {
+	return func(l *Lexer) {
+		l.hashComments = true
+	}
+}
+
+// WithIntegerDivision makes the lexer read "//" as the INT_DIV operator instead of
+// the start of a line comment. Since "//" can no longer start a comment, '#' is then
+// the only way to write one, if enabled with WithHashComments. Without this option,
+// comments win and "a // b" lexes as the identifier a followed by a comment.
+func WithIntegerDivision() Option {
+	return func(l *Lexer) {
+		l.intDiv = true
+		l.operators = intDivTrie
+	}
+}
+
+// WithCaseInsensitiveKeywords makes the lexer match keywords regardless of case, so
+// that "if", "If" and "IF" are all the IF keyword. The literal of a keyword keeps the
+// case it was written in.
+func WithCaseInsensitiveKeywords() Option {
+	return func(l *Lexer) {
+		l.foldKeywords = true
+	}
+}
+
+// endsStatement is the set of token types that can end a statement, after which
+// a virtual semicolon may be inserted.
+var endsStatement = map[token.TokenType]bool{
+	token.IDENT:    true,
+	token.INT:      true,
+	token.STRING:   true,
+	token.TEMPLATE: true,
+	token.TRUE:     true,
+	token.FALSE:    true,
+	token.RPAREN:   true,
+	token.RBRACE:   true,
+}
+
+// WithSemicolonInsertion makes the lexer insert virtual semicolons so that the last
+// statement of a block or of the input does not need one, similar to Go. A SEMICOLON
+// token with an empty literal is inserted before a '}' or the EOF if the token
+// preceding it is one of:
+//
+//   - an identifier;
+//   - an integer, string or template literal, or true or false;
+//   - a closing ')' or '}'.
+//
+// No semicolon is inserted after any other token, such as an operator, a keyword,
+// a '{' or an explicit ';'. The virtual semicolon is positioned, with zero width,
+// right after the preceding token.
+//
+// Semicolons are only inserted between top-level tokens, never among the tokens of
+// a template's ${...} expression. Virtual semicolons are not subject to
+// WithDisallowed, so disallowing SEMICOLON rejects only the ones written in the
+// input, e.g. to require relying on insertion.
+func WithSemicolonInsertion() Option {
+	return func(l *Lexer) {
+		l.insertSemicolons = true
+	}
+}
+
+// WithDisallowed makes the lexer reject tokens of the given types, emitting an
+// ERROR token in their place. This allows embedders to restrict the language to
+// a subset, e.g. forbidding function literals. Disallowing ILLEGAL reports unknown
+// characters as ERROR tokens too, so that ERROR is the only kind of bad token.
+// EOF and ERROR cannot be disallowed and are ignored: the input must always be
+// able to end, and an ERROR token keeps its own message.
+func WithDisallowed(types ...token.TokenType) Option {
+	return func(l *Lexer) {
+		if l.disallowed == nil {
+			l.disallowed = make(map[token.TokenType]bool)
+		}
+		for _, t := range types {
+			if t != token.EOF && t != token.ERROR {
+				l.disallowed[t] = true
+			}
+		}
+	}
+}
+
+// Limits sets the maximum length in bytes of the literals of some token types, to
+// guard against pathological untrusted input. A limit of zero means no limit.
+type Limits struct {
+	Identifier int // Maximum length of identifiers
+	String     int // Maximum length of string and template literals, as in their Literal
+	Number     int // Maximum length of integer literals
+}
+
+// limit returns the limit for tokens of type t, if any, and the kind of literal it
+// applies to.
+func (lim Limits) limit(t token.TokenType) (int, string) {
+	switch t {
+	case token.IDENT:
+		return lim.Identifier, "identifier"
+	case token.STRING, token.TEMPLATE:
+		return lim.String, "string"
+	case token.INT:
+		return lim.Number, "number"
+	}
+	return 0, ""
+}
+
+// WithLimits makes the lexer emit an ERROR token in place of a literal longer than
+// its limit. Without it, literals can be of any length.
+func WithLimits(limits Limits) Option {
+	return func(l *Lexer) {
+		l.limits = limits
+	}
 }
 
 // New initializes a new Lexer for the given input string.
-func New(input string) *Lexer {
-	l := &Lexer{input: input}
+func New(input string, opts ...Option) *Lexer {
+	l := &Lexer{input: input, line: 1, lineStarts: []int{0}, operators: operatorTrie}
+	for _, opt := range opts {
+		opt(l)
+	}
 	l.readChar()
+	l.skipShebang()
 	return l
 }
 
+// skipShebang skips the first line of the input if it starts with '#', so that scripts
+// can begin with an interpreter line like "#!/usr/bin/env monkey". A '#' anywhere else
+// is not special.
+func (l *Lexer) skipShebang() {
+	if l.ch != '#' {
+		return
+	}
+	for l.ch != '\n' && !l.atEOF() {
+		l.readChar()
+	}
+}
+
 // readChar advances the lexer to the next character in the input.
-// Sets l.ch to 0 if the end of the input is reached (EOF).
+// Sets l.ch to 0 if the end of the input is reached (EOF), after which it no longer advances.
 func (l *Lexer) readChar() {
+	if l.readPosition > len(l.input) {
+		return
+	}
+	if l.ch == '\n' {
+		l.line += 1
+		l.lineStart = l.readPosition
+		if l.line == len(l.lineStarts)+1 {
+			l.lineStarts = append(l.lineStarts, l.lineStart)
+		}
+	}
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -31,63 +206,171 @@ func (l *Lexer) readChar() {
 
 // NextToken retrieves the next token from the input and advances the lexer.
 func (l *Lexer) NextToken() token.Token {
+	if len(l.buffer) > 0 {
+		tok := l.buffer[0]
+		l.buffer = l.buffer[1:]
+		return tok
+	}
+	return l.scanToken()
+}
+
+// PeekN returns the nth upcoming token without consuming it, so that PeekN(1)
+// is the token the next call to NextToken will return. It panics if n < 1.
+func (l *Lexer) PeekN(n int) token.Token {
+	if n < 1 {
+		panic("lexer: PeekN called with n < 1")
+	}
+	for len(l.buffer) < n {
+		l.buffer = append(l.buffer, l.scanToken())
+	}
+	return l.buffer[n-1]
+}
+
+// LexerState is a snapshot of the lexer's position in the input, taken by Mark and
+// returned to by Restore.
+type LexerState struct {
+	position     int
+	readPosition int
+	ch           byte
+	line         int
+	lineStart    int
+	buffer       []token.Token
+	last         token.Token
+	pending      *token.Token
+}
+
+// Mark returns the current state of the lexer, so that a parser can consume tokens
+// speculatively and then backtrack to it with Restore. Tokens peeked with PeekN but
+// not yet consumed are part of the state.
+func (l *Lexer) Mark() LexerState {
+	return LexerState{
+		position:     l.position,
+		readPosition: l.readPosition,
+		ch:           l.ch,
+		line:         l.line,
+		lineStart:    l.lineStart,
+		buffer:       slices.Clone(l.buffer),
+		last:         l.last,
+		pending:      l.pending,
+	}
+}
+
+// Restore returns the lexer to a state taken by Mark, so that the following tokens
+// are lexed again from there. A state can be restored any number of times.
+func (l *Lexer) Restore(s LexerState) {
+	l.position = s.position
+	l.readPosition = s.readPosition
+	l.ch = s.ch
+	l.line = s.line
+	l.lineStart = s.lineStart
+	l.buffer = s.buffer
+	l.last = s.last
+	l.pending = s.pending
+}
+
+// scanToken scans the next token from the input, inserting virtual semicolons if
+// the lexer is configured to.
+func (l *Lexer) scanToken() token.Token {
 	var tok token.Token
+	if l.pending != nil {
+		tok, l.pending = *l.pending, nil
+	} else {
+		tok = l.lexToken()
+		if l.insertSemicolons && (tok.Type == token.RBRACE || tok.Type == token.EOF) && endsStatement[l.last.Type] {
+			pending := tok
+			l.pending = &pending
+			tok = token.Token{Type: token.SEMICOLON, Pos: l.last.End, End: l.last.End}
+		}
+	}
+	l.last = tok
+	return tok
+}
+
+// lexToken lexes the next token from the input.
+func (l *Lexer) lexToken() token.Token {
+	comments := l.skipWhitespace()
+	pos := l.currentPosition()
+	tok := l.readToken()
+
+	if l.disallowed[tok.Type] {
+		tok = newErrorToken("%q (%s) is not allowed", tok.Literal, tok.Type)
+	} else if limit, kind := l.limits.limit(tok.Type); limit > 0 && len(tok.Literal) > limit {
+		tok = newErrorToken("%s longer than %d bytes", kind, limit)
+	}
+	tok.Pos = pos
+	tok.End = l.currentPosition()
+
+	if l.preserveComments {
+		tok.Comments = comments
+		tok.Trailing = l.readTrailingComment()
+	}
+	return tok
+}
 
-	l.skipWhitespace()
+// Line returns the text of the nth line of the input (1-based), without its line
+// terminator, or "" if there is no such line.
+func (l *Lexer) Line(n int) string {
+	if n < 1 {
+		return ""
+	}
+	for n > len(l.lineStarts) {
+		last := l.lineStarts[len(l.lineStarts)-1]
+		next := strings.IndexByte(l.input[last:], '\n')
+		if next < 0 {
+			return ""
+		}
+		l.lineStarts = append(l.lineStarts, last+next+1)
+	}
+
+	line := l.input[l.lineStarts[n-1]:]
+	if end := strings.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	return strings.TrimSuffix(line, "\r")
+}
+
+// currentPosition returns the source position of the current character.
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{
+		Offset: l.position,
+		Line:   l.line,
+		Column: l.position - l.lineStart + 1,
+	}
+}
+
+// readToken reads the token starting at the current character.
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
-	case '=':
-		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			tok = newTwoCharToken(token.EQ, ch, l.ch)
-		} else {
-			tok = newToken(token.ASSIGN, l.ch)
-		}
-	case '+':
-		tok = newToken(token.PLUS, l.ch)
-	case '-':
-		tok = newToken(token.MINUS, l.ch)
-	case '!':
-		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			tok = newTwoCharToken(token.NOT_EQ, ch, l.ch)
-		} else {
-			tok = newToken(token.BANG, l.ch)
-		}
-	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
-	case '/':
-		tok = newToken(token.SLASH, l.ch)
-	case '<':
-		tok = newToken(token.LT, l.ch)
-	case '>':
-		tok = newToken(token.GT, l.ch)
-	case ',':
-		tok = newToken(token.COMMA, l.ch)
-	case ';':
-		tok = newToken(token.SEMICOLON, l.ch)
-	case '(':
-		tok = newToken(token.LPAREN, l.ch)
-	case ')':
-		tok = newToken(token.RPAREN, l.ch)
-	case '{':
-		tok = newToken(token.LBRACE, l.ch)
-	case '}':
-		tok = newToken(token.RBRACE, l.ch)
+	case '"':
+		return l.readString()
 	case 0:
+		if !l.atEOF() {
+			tok = newToken(token.ILLEGAL, l.ch)
+			break
+		}
 		tok.Literal = ""
 		tok.Type = token.EOF
+		return tok
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
-			tok.Type = token.LookupIdent(tok.Literal)
+			if l.foldKeywords {
+				tok.Type = token.LookupIdent(strings.ToLower(tok.Literal))
+			} else {
+				tok.Type = token.LookupIdent(tok.Literal)
+			}
 			return tok
 		} else if isDigit(l.ch) {
 			tok.Type = token.INT
 			tok.Literal = l.readNumber()
 			return tok
+		} else if op, ok := l.readOperator(); ok {
+			if op.Type == heredocStart {
+				return l.readHeredoc()
+			}
+			tok = op
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
@@ -106,16 +389,260 @@ func (l *Lexer) readNumber() string {
 	return l.input[position:l.position]
 }
 
+// readString reads a double-quoted string literal and decodes its escape sequences.
+// A string containing embedded expressions, like "hello ${name}", is read as a single
+// TEMPLATE token whose Parts hold its literal segments and the tokens of each
+// expression; its literal is the source text between the quotes. A "$" not followed
+// by "{" is literal text, and "\$" always is. It returns an ERROR token if the string
+// or an embedded expression is unterminated, or if it contains a malformed escape.
+func (l *Lexer) readString() token.Token {
+	start := l.position
+	var sb strings.Builder
+	var parts []token.TemplatePart
+	var errMsg string
+
+	for {
+		l.readChar()
+		switch {
+		case l.atEOF():
+			return newErrorToken("unterminated string")
+		case l.ch == '"':
+			literal := l.input[start+1 : l.position]
+			l.readChar()
+			if errMsg != "" {
+				return newErrorToken("%s", errMsg)
+			}
+			if parts == nil {
+				return token.Token{Type: token.STRING, Literal: sb.String()}
+			}
+			if sb.Len() > 0 {
+				parts = append(parts, token.TemplatePart{Text: sb.String()})
+			}
+			return token.Token{Type: token.TEMPLATE, Literal: literal, Parts: parts}
+		case l.ch == '\\':
+			escape := l.position
+			l.readChar()
+			if !l.readEscape(&sb) && errMsg == "" {
+				errMsg = fmt.Sprintf("invalid escape sequence %q", l.input[escape:min(l.position+1, len(l.input))])
+			}
+		case l.ch == '$' && l.peekChar() == '{':
+			if sb.Len() > 0 {
+				parts = append(parts, token.TemplatePart{Text: sb.String()})
+				sb.Reset()
+			}
+			l.readChar()
+			l.readChar()
+			expr, ok := l.readTemplateExpr()
+			if !ok {
+				return newErrorToken("unterminated template expression")
+			}
+			parts = append(parts, token.TemplatePart{Expr: expr})
+		default:
+			sb.WriteByte(l.ch)
+		}
+	}
+}
+
+// readTemplateExpr reads the tokens of an embedded ${...} expression, starting at the
+// current character, just past "${". Braces may nest inside the expression. It leaves
+// the lexer on the closing '}' and reports false if the input ends before it.
+func (l *Lexer) readTemplateExpr() ([]token.Token, bool) {
+	expr := []token.Token{}
+	depth := 0
+
+	for {
+		l.skipWhitespace()
+		if l.atEOF() {
+			return nil, false
+		}
+		if l.ch == '}' && depth == 0 {
+			return expr, true
+		}
+
+		tok := l.lexToken()
+		switch tok.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+		}
+		expr = append(expr, tok)
+	}
+}
+
+// readHeredoc reads a heredoc, a multi-line string literal like
+//
+//	<<<END
+//	first line
+//	  second line
+//	END
+//
+// as a STRING token holding the lines between the opening and the closing delimiter.
+// The delimiter is an identifier that must end the opening line, and that closes the
+// heredoc on a line of its own, where it may be surrounded by spaces and tabs. The
+// text is taken verbatim: escapes are not processed and indentation is not stripped.
+// The newline before the closing delimiter is not part of the string. It starts on
+// the last '<' of "<<<" and returns an ERROR token if the delimiter is missing or
+// does not end the line, or if the input ends before the closing delimiter.
+func (l *Lexer) readHeredoc() token.Token {
+	l.readChar()
+
+	if !isLetter(l.ch) {
+		return newErrorToken("missing heredoc delimiter")
+	}
+	delimiter := l.readIdentifier()
+	if l.ch == '\r' {
+		l.readChar()
+	}
+	if l.ch != '\n' {
+		return newErrorToken("heredoc delimiter %q must end the line", delimiter)
+	}
+
+	l.readChar()
+	start := l.position
+	for !l.atEOF() {
+		lineStart := l.position
+		for l.ch != '\n' && !l.atEOF() {
+			l.readChar()
+		}
+		if strings.Trim(l.input[lineStart:l.position], " \t\r") == delimiter {
+			body := l.input[start:max(start, lineStart-1)]
+			return token.Token{Type: token.STRING, Literal: strings.TrimSuffix(body, "\r")}
+		}
+		l.readChar()
+	}
+	return newErrorToken("unterminated heredoc")
+}
+
+// readEscape decodes the escape sequence whose first character (after the backslash)
+// is the current character, writing the result to sb. It leaves the lexer on the last
+// character of the sequence and reports whether the sequence was valid.
+//
+// Besides the single-character escapes, the following are supported, each producing
+// the UTF-8 encoding of the given code point:
+//
+//	\xNN      exactly two hex digits
+//	\uNNNN    exactly four hex digits
+//	\u{N...}  one to six hex digits
+func (l *Lexer) readEscape(sb *strings.Builder) bool {
+	switch l.ch {
+	case 'n':
+		sb.WriteByte('\n')
+	case 't':
+		sb.WriteByte('\t')
+	case 'r':
+		sb.WriteByte('\r')
+	case '"':
+		sb.WriteByte('"')
+	case '\\':
+		sb.WriteByte('\\')
+	case '$':
+		sb.WriteByte('$')
+	case 'x':
+		return l.readCodePoint(sb, l.readHexDigits(2), 2)
+	case 'u':
+		if l.peekChar() != '{' {
+			return l.readCodePoint(sb, l.readHexDigits(4), 4)
+		}
+		l.readChar()
+		digits := l.readHexDigits(6)
+		if l.peekChar() != '}' {
+			return false
+		}
+		l.readChar()
+		return digits != "" && l.readCodePoint(sb, digits, len(digits))
+	default:
+		return false
+	}
+	return true
+}
+
+// readHexDigits consumes up to max hex digits following the current character.
+func (l *Lexer) readHexDigits(max int) string {
+	position := l.position + 1
+	for i := 0; i < max && isHexDigit(l.peekChar()); i++ {
+		l.readChar()
+	}
+	return l.input[position : l.position+1]
+}
+
+// readCodePoint writes the UTF-8 encoding of the code point given by the hex digits
+// to sb, reporting whether there were exactly n digits forming a valid code point.
+func (l *Lexer) readCodePoint(sb *strings.Builder, digits string, n int) bool {
+	if len(digits) != n {
+		return false
+	}
+	r, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || !utf8.ValidRune(rune(r)) {
+		return false
+	}
+	sb.WriteRune(rune(r))
+	return true
+}
+
+// isHexDigit checks if the given character is a hexadecimal digit.
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
 // isDigit checks if the given character is a digit ('0' to '9').
 func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-// skipWhitespace skips over whitespace characters like spaces, tabs, and newlines.
-func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+// skipWhitespace skips over whitespace characters like spaces, tabs, and newlines,
+// as well as comments. The skipped comments are returned if the lexer preserves them.
+func (l *Lexer) skipWhitespace() []token.Token {
+	var comments []token.Token
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			l.readChar()
+		case l.atComment():
+			comment := l.readComment()
+			if l.preserveComments {
+				comments = append(comments, comment)
+			}
+		default:
+			return comments
+		}
+	}
+}
+
+// atComment reports whether a line comment starts at the current character.
+func (l *Lexer) atComment() bool {
+	return !l.intDiv && l.ch == '/' && l.peekChar() == '/' || l.hashComments && l.ch == '#'
+}
+
+// readComment reads a line comment, from its leading "//" or '#' up to the end of the line.
+func (l *Lexer) readComment() token.Token {
+	pos := l.currentPosition()
+	for l.ch != '\n' && !l.atEOF() {
 		l.readChar()
 	}
+	return token.Token{
+		Type:    token.COMMENT,
+		Literal: l.input[pos.Offset:l.position],
+		Pos:     pos,
+		End:     l.currentPosition(),
+	}
+}
+
+// readTrailingComment reads the comment that ends the current line, if any.
+func (l *Lexer) readTrailingComment() *token.Token {
+	for l.ch == ' ' || l.ch == '\t' {
+		l.readChar()
+	}
+	if l.atComment() {
+		comment := l.readComment()
+		return &comment
+	}
+	return nil
+}
+
+// atEOF reports whether the lexer has consumed the whole input.
+func (l *Lexer) atEOF() bool {
+	return l.position >= len(l.input)
 }
 
 func (l *Lexer) peekChar() byte {
@@ -126,10 +653,11 @@ func (l *Lexer) peekChar() byte {
 	}
 }
 
-// readIdentifier reads an identifier (a sequence of letters or underscores) from the input.
+// readIdentifier reads an identifier (a letter or underscore followed by letters, underscores
+// or digits) from the input.
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
@@ -148,11 +676,10 @@ func newToken(tokenType token.TokenType, ch byte) token.Token {
 	}
 }
 
-// newTwoCharToken creates a new token with the given type and a literal value
-// formed by concatenating two characters (ch1 and ch2).
-func newTwoCharToken(tokenType token.TokenType, ch1 byte, ch2 byte) token.Token {
+// newErrorToken creates an ERROR token whose literal is the formatted error message.
+func newErrorToken(format string, args ...any) token.Token {
 	return token.Token{
-		Type:    tokenType,
-		Literal: string(ch1) + string(ch2),
+		Type:    token.ERROR,
+		Literal: fmt.Sprintf(format, args...),
 	}
 }
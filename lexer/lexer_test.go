@@ -25,6 +25,12 @@ func TestNextToken(t *testing.T) {
 		}
 		10 == 10;
 		10 != 9;
+		const max = 10;
+		x > 0 ? x : -x;
+		f(...args);
+		import "math";
+		do { x } while (false)
+		switch (x) { case 1: "one" default: "many" }
 	`
 
 	tests := []struct {
@@ -104,6 +110,50 @@ func TestNextToken(t *testing.T) {
 		{token.NOT_EQ, "!="},
 		{token.INT, "9"},
 		{token.SEMICOLON, ";"},
+		{token.CONST, "const"},
+		{token.IDENT, "max"},
+		{token.ASSIGN, "="},
+		{token.INT, "10"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.GT, ">"},
+		{token.INT, "0"},
+		{token.QUESTION, "?"},
+		{token.IDENT, "x"},
+		{token.COLON, ":"},
+		{token.MINUS, "-"},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "args"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.IMPORT, "import"},
+		{token.STRING, "math"},
+		{token.SEMICOLON, ";"},
+		{token.DO, "do"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "x"},
+		{token.RBRACE, "}"},
+		{token.WHILE, "while"},
+		{token.LPAREN, "("},
+		{token.FALSE, "false"},
+		{token.RPAREN, ")"},
+		{token.SWITCH, "switch"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.CASE, "case"},
+		{token.INT, "1"},
+		{token.COLON, ":"},
+		{token.STRING, "one"},
+		{token.DEFAULT, "default"},
+		{token.COLON, ":"},
+		{token.STRING, "many"},
+		{token.RBRACE, "}"},
 		{token.EOF, ""},
 	}
 
@@ -124,3 +174,976 @@ func TestNextToken(t *testing.T) {
 		t.Fatalf("expected EOF token at the end of input, got %q", tok.Type)
 	}
 }
+
+func TestLineComments(t *testing.T) {
+	input := `// leading comment
+		let x = 5; // trailing comment
+		x / 2; /// more slashes
+		x / / 2;
+	`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestPreserveComments(t *testing.T) {
+	input := `// add returns the sum of x and y.
+		// It works on integers.
+		let add = fn(x, y) { x + y }; // trailing comment
+
+		// result holds the answer.
+		let result = add(1, 2);
+		// dangling comment`
+
+	tests := []struct {
+		expectedType     token.TokenType
+		expectedComments []string
+		expectedTrailing string
+	}{
+		{token.LET, []string{"// add returns the sum of x and y.", "// It works on integers."}, ""},
+		{token.IDENT, nil, ""},
+		{token.ASSIGN, nil, ""},
+		{token.FUNCTION, nil, ""},
+		{token.LPAREN, nil, ""},
+		{token.IDENT, nil, ""},
+		{token.COMMA, nil, ""},
+		{token.IDENT, nil, ""},
+		{token.RPAREN, nil, ""},
+		{token.LBRACE, nil, ""},
+		{token.IDENT, nil, ""},
+		{token.PLUS, nil, ""},
+		{token.IDENT, nil, ""},
+		{token.RBRACE, nil, ""},
+		{token.SEMICOLON, nil, "// trailing comment"},
+		{token.LET, []string{"// result holds the answer."}, ""},
+		{token.IDENT, nil, ""},
+		{token.ASSIGN, nil, ""},
+		{token.IDENT, nil, ""},
+		{token.LPAREN, nil, ""},
+		{token.INT, nil, ""},
+		{token.COMMA, nil, ""},
+		{token.INT, nil, ""},
+		{token.RPAREN, nil, ""},
+		{token.SEMICOLON, nil, ""},
+		{token.EOF, []string{"// dangling comment"}, ""},
+	}
+
+	l := New(input, WithComments())
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if len(tok.Comments) != len(tt.expectedComments) {
+			t.Fatalf("tests[%d] - wrong number of comments. expected %d, got %d", i, len(tt.expectedComments), len(tok.Comments))
+		}
+
+		for j, comment := range tok.Comments {
+			if comment.Type != token.COMMENT {
+				t.Fatalf("tests[%d] - comments[%d] tokentype wrong. expected %q, got %q", i, j, token.COMMENT, comment.Type)
+			}
+			if comment.Literal != tt.expectedComments[j] {
+				t.Fatalf("tests[%d] - comments[%d] literal wrong. expected %q, got %q", i, j, tt.expectedComments[j], comment.Literal)
+			}
+		}
+
+		if tt.expectedTrailing == "" {
+			if tok.Trailing != nil {
+				t.Fatalf("tests[%d] - unexpected trailing comment %q", i, tok.Trailing.Literal)
+			}
+			continue
+		}
+
+		if tok.Trailing == nil {
+			t.Fatalf("tests[%d] - expected trailing comment %q, got none", i, tt.expectedTrailing)
+		}
+
+		if tok.Trailing.Literal != tt.expectedTrailing {
+			t.Fatalf("tests[%d] - trailing comment wrong. expected %q, got %q", i, tt.expectedTrailing, tok.Trailing.Literal)
+		}
+	}
+
+	for i, tok := range Tokenize(input) {
+		if tok.Comments != nil || tok.Trailing != nil {
+			t.Fatalf("tokens[%d] - comments attached without WithComments", i)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x == 10\n// comment\n!y"
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+	}{
+		{token.LET, token.Position{Offset: 0, Line: 1, Column: 1}},
+		{token.IDENT, token.Position{Offset: 4, Line: 1, Column: 5}},
+		{token.ASSIGN, token.Position{Offset: 6, Line: 1, Column: 7}},
+		{token.INT, token.Position{Offset: 8, Line: 1, Column: 9}},
+		{token.SEMICOLON, token.Position{Offset: 9, Line: 1, Column: 10}},
+		{token.IDENT, token.Position{Offset: 13, Line: 2, Column: 3}},
+		{token.EQ, token.Position{Offset: 15, Line: 2, Column: 5}},
+		{token.INT, token.Position{Offset: 18, Line: 2, Column: 8}},
+		{token.BANG, token.Position{Offset: 32, Line: 4, Column: 1}},
+		{token.IDENT, token.Position{Offset: 33, Line: 4, Column: 2}},
+		{token.EOF, token.Position{Offset: 34, Line: 4, Column: 3}},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - position wrong. expected %+v, got %+v", i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestDisallowedTokens(t *testing.T) {
+	input := `let add = fn(x, y) { x + y };`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "add"},
+		{token.ASSIGN, "="},
+		{token.ERROR, `"fn" (FUNCTION) is not allowed`},
+		{token.LPAREN, "("},
+	}
+
+	l := New(input, WithDisallowed(token.FUNCTION))
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestDisallowedSpecialTokens(t *testing.T) {
+	input := `x @ "\q"`
+
+	expected := []token.Token{
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.ERROR, Literal: `"@" (ILLEGAL) is not allowed`},
+		{Type: token.ERROR, Literal: `invalid escape sequence "\\q"`},
+		{Type: token.EOF, Literal: ""},
+	}
+	token.AssertTokens(t, Tokenize(input, WithDisallowed(token.ILLEGAL, token.ERROR, token.EOF)), expected)
+}
+
+func TestPeekN(t *testing.T) {
+	input := `let x = 5;`
+
+	l := New(input)
+
+	if tok := l.PeekN(2); tok.Type != token.IDENT || tok.Literal != "x" {
+		t.Fatalf("PeekN(2) wrong. expected IDENT \"x\", got %q %q", tok.Type, tok.Literal)
+	}
+
+	if tok := l.PeekN(3); tok.Type != token.ASSIGN {
+		t.Fatalf("PeekN(3) wrong. expected %q, got %q", token.ASSIGN, tok.Type)
+	}
+
+	if tok := l.PeekN(1); tok.Type != token.LET {
+		t.Fatalf("PeekN(1) wrong. expected %q, got %q", token.LET, tok.Type)
+	}
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+	}{
+		{token.LET, token.Position{Offset: 0, Line: 1, Column: 1}},
+		{token.IDENT, token.Position{Offset: 4, Line: 1, Column: 5}},
+		{token.ASSIGN, token.Position{Offset: 6, Line: 1, Column: 7}},
+		{token.INT, token.Position{Offset: 8, Line: 1, Column: 9}},
+		{token.SEMICOLON, token.Position{Offset: 9, Line: 1, Column: 10}},
+		{token.EOF, token.Position{Offset: 10, Line: 1, Column: 11}},
+	}
+
+	for i, tt := range tests {
+		if i == 3 {
+			if tok := l.PeekN(3); tok.Type != token.EOF {
+				t.Fatalf("PeekN(3) past the end wrong. expected %q, got %q", token.EOF, tok.Type)
+			}
+		}
+
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - position wrong. expected %+v, got %+v", i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{`"foobar"`, token.STRING, "foobar"},
+		{`"foo bar"`, token.STRING, "foo bar"},
+		{`""`, token.STRING, ""},
+		{`"a\nb\t\"c\"\\"`, token.STRING, "a\nb\t\"c\"\\"},
+		{`"\x41"`, token.STRING, "A"},
+		{`"\xe9"`, token.STRING, "é"},
+		{`"é"`, token.STRING, "é"},
+		{`"\u{1F600}!"`, token.STRING, "😀!"},
+		{`"\u{41}"`, token.STRING, "A"},
+		{`"\x4"`, token.ERROR, `invalid escape sequence "\\x4"`},
+		{`"\xzz"`, token.ERROR, `invalid escape sequence "\\x"`},
+		{`"\u00e"`, token.ERROR, `invalid escape sequence "\\u00e"`},
+		{`"\u{}"`, token.ERROR, `invalid escape sequence "\\u{}"`},
+		{`"\u{110000}"`, token.ERROR, `invalid escape sequence "\\u{110000}"`},
+		{`"\u{1F600"`, token.ERROR, `invalid escape sequence "\\u{1F600"`},
+		{`"\ud800"`, token.ERROR, `invalid escape sequence "\\ud800"`},
+		{`"\q"`, token.ERROR, `invalid escape sequence "\\q"`},
+		{`"unterminated`, token.ERROR, "unterminated string"},
+		{`"trailing backslash\`, token.ERROR, "unterminated string"},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Fatalf("tests[%d] - expected EOF after the string, got %q", i, tok.Type)
+		}
+	}
+}
+
+func TestTokenEnd(t *testing.T) {
+	input := "let greeting = \"hi\\n\"; // done\nfoo != 10"
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedText string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "greeting"},
+		{token.ASSIGN, "="},
+		{token.STRING, `"hi\n"`},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "foo"},
+		{token.NOT_EQ, "!="},
+		{token.INT, "10"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if text := input[tok.Pos.Offset:tok.End.Offset]; text != tt.expectedText {
+			t.Fatalf("tests[%d] - source text wrong. expected %q, got %q", i, tt.expectedText, text)
+		}
+	}
+}
+
+func TestIncompleteEllipsis(t *testing.T) {
+	input := `. .. ... ....`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.ILLEGAL, "."},
+		{token.ILLEGAL, ".."},
+		{token.ELLIPSIS, "..."},
+		{token.ELLIPSIS, "..."},
+		{token.ILLEGAL, "."},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestLine(t *testing.T) {
+	input := "let x = 5;\r\n\nlet y = x\n  * 2;"
+
+	l := New(input)
+
+	// Lines can be retrieved before the lexer reaches them.
+	if line := l.Line(3); line != "let y = x" {
+		t.Fatalf("Line(3) before lexing wrong. expected %q, got %q", "let y = x", line)
+	}
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type == token.ASTERISK && l.Line(tok.Pos.Line) != "  * 2;" {
+			t.Fatalf("line of %q wrong. expected %q, got %q", tok.Literal, "  * 2;", l.Line(tok.Pos.Line))
+		}
+	}
+
+	tests := []struct {
+		n        int
+		expected string
+	}{
+		{1, "let x = 5;"},
+		{2, ""},
+		{3, "let y = x"},
+		{4, "  * 2;"},
+		{5, ""},
+		{0, ""},
+	}
+
+	for i, tt := range tests {
+		if line := l.Line(tt.n); line != tt.expected {
+			t.Errorf("tests[%d] - Line(%d) wrong. expected %q, got %q", i, tt.n, tt.expected, line)
+		}
+	}
+}
+
+func TestShebang(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{
+			"#!/usr/bin/env monkey\nlet x = 1;",
+			[]token.Token{
+				{Type: token.LET, Literal: "let"},
+				{Type: token.IDENT, Literal: "x"},
+				{Type: token.ASSIGN, Literal: "="},
+				{Type: token.INT, Literal: "1"},
+				{Type: token.SEMICOLON, Literal: ";"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			"#!/usr/bin/env monkey",
+			[]token.Token{
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			"x # y\n#!z",
+			[]token.Token{
+				{Type: token.IDENT, Literal: "x"},
+				{Type: token.ILLEGAL, Literal: "#"},
+				{Type: token.IDENT, Literal: "y"},
+				{Type: token.ILLEGAL, Literal: "#"},
+				{Type: token.BANG, Literal: "!"},
+				{Type: token.IDENT, Literal: "z"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			" #!x",
+			[]token.Token{
+				{Type: token.ILLEGAL, Literal: "#"},
+				{Type: token.BANG, Literal: "!"},
+				{Type: token.IDENT, Literal: "x"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		token.AssertTokens(t, Tokenize(tt.input), tt.expected)
+	}
+
+	if tok := New("#!/bin/monkey\nx").NextToken(); tok.Pos.Line != 2 || tok.Pos.Column != 1 {
+		t.Fatalf("position after shebang wrong. expected 2:1, got %s", tok.Pos)
+	}
+}
+
+func TestHashComments(t *testing.T) {
+	input := "#!/usr/bin/env monkey\nlet x = 1; # one\n# doc\nx"
+
+	enabled := []token.Token{
+		{Type: token.LET, Literal: "let"},
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.ASSIGN, Literal: "="},
+		{Type: token.INT, Literal: "1"},
+		{Type: token.SEMICOLON, Literal: ";"},
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.EOF, Literal: ""},
+	}
+	token.AssertTokens(t, Tokenize(input, WithHashComments()), enabled)
+
+	disabled := []token.Token{
+		{Type: token.LET, Literal: "let"},
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.ASSIGN, Literal: "="},
+		{Type: token.INT, Literal: "1"},
+		{Type: token.SEMICOLON, Literal: ";"},
+		{Type: token.ILLEGAL, Literal: "#"},
+		{Type: token.IDENT, Literal: "one"},
+		{Type: token.ILLEGAL, Literal: "#"},
+		{Type: token.IDENT, Literal: "doc"},
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.EOF, Literal: ""},
+	}
+	token.AssertTokens(t, Tokenize(input), disabled)
+
+	tokens := Tokenize(input, WithHashComments(), WithComments())
+	if trailing := tokens[4].Trailing; trailing == nil || trailing.Literal != "# one" {
+		t.Fatalf("expected trailing comment %q, got %+v", "# one", trailing)
+	}
+	if comments := tokens[5].Comments; len(comments) != 1 || comments[0].Literal != "# doc" {
+		t.Fatalf("expected leading comment %q, got %+v", "# doc", comments)
+	}
+}
+
+func TestTemplateStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+		expectedParts   []token.TemplatePart
+	}{
+		{
+			`"hello ${name}!"`,
+			`hello ${name}!`,
+			[]token.TemplatePart{
+				{Text: "hello "},
+				{Expr: []token.Token{{Type: token.IDENT, Literal: "name"}}},
+				{Text: "!"},
+			},
+		},
+		{
+			`"${ f({"a": 1}) }"`,
+			`${ f({"a": 1}) }`,
+			[]token.TemplatePart{
+				{Expr: []token.Token{
+					{Type: token.IDENT, Literal: "f"},
+					{Type: token.LPAREN, Literal: "("},
+					{Type: token.LBRACE, Literal: "{"},
+					{Type: token.STRING, Literal: "a"},
+					{Type: token.COLON, Literal: ":"},
+					{Type: token.INT, Literal: "1"},
+					{Type: token.RBRACE, Literal: "}"},
+					{Type: token.RPAREN, Literal: ")"},
+				}},
+			},
+		},
+		{
+			`"a${x}${y}\n"`,
+			`a${x}${y}\n`,
+			[]token.TemplatePart{
+				{Text: "a"},
+				{Expr: []token.Token{{Type: token.IDENT, Literal: "x"}}},
+				{Expr: []token.Token{{Type: token.IDENT, Literal: "y"}}},
+				{Text: "\n"},
+			},
+		},
+		{
+			`"outer ${"inner ${x}"}"`,
+			`outer ${"inner ${x}"}`,
+			[]token.TemplatePart{
+				{Text: "outer "},
+				{Expr: []token.Token{{Type: token.TEMPLATE, Literal: "inner ${x}"}}},
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != token.TEMPLATE {
+			t.Fatalf("tests[%d] - tokentype wrong. expected %q, got %q", i, token.TEMPLATE, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if len(tok.Parts) != len(tt.expectedParts) {
+			t.Fatalf("tests[%d] - wrong number of parts. expected %d, got %d", i, len(tt.expectedParts), len(tok.Parts))
+		}
+
+		for j, part := range tok.Parts {
+			want := tt.expectedParts[j]
+			if (part.Expr == nil) != (want.Expr == nil) || part.Text != want.Text {
+				t.Fatalf("tests[%d] - parts[%d] wrong. expected %+v, got %+v", i, j, want, part)
+			}
+			token.AssertTokens(t, part.Expr, want.Expr)
+		}
+
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Fatalf("tests[%d] - expected EOF after the string, got %q", i, tok.Type)
+		}
+	}
+}
+
+func TestTemplateStringPositions(t *testing.T) {
+	input := "let s = \"a ${\n  x\n}\";"
+
+	l := New(input)
+	for range 3 {
+		l.NextToken()
+	}
+
+	tok := l.NextToken()
+	if tok.Type != token.TEMPLATE {
+		t.Fatalf("tokentype wrong. expected %q, got %q", token.TEMPLATE, tok.Type)
+	}
+
+	expr := tok.Parts[1].Expr[0]
+	if expected := (token.Position{Offset: 16, Line: 2, Column: 3}); expr.Pos != expected {
+		t.Fatalf("embedded token position wrong. expected %+v, got %+v", expected, expr.Pos)
+	}
+
+	if tok := l.NextToken(); tok.Type != token.SEMICOLON || tok.Pos.Line != 3 {
+		t.Fatalf("expected SEMICOLON on line 3 after the template, got %q at %s", tok.Type, tok.Pos)
+	}
+}
+
+func TestNonTemplateDollars(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{`"cost: $5"`, token.STRING, "cost: $5"},
+		{`"\${name}"`, token.STRING, "${name}"},
+		{`"$"`, token.STRING, "$"},
+		{`"a ${x"`, token.ERROR, "unterminated template expression"},
+		{`"a ${x} b`, token.ERROR, "unterminated string"},
+		{`"${x} \q"`, token.ERROR, `invalid escape sequence "\\q"`},
+	}
+
+	for i, tt := range tests {
+		tok := New(tt.input).NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestSemicolonInsertion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{
+			"let add = fn(x, y) { x + y }",
+			[]token.Token{
+				{Type: token.LET, Literal: "let"},
+				{Type: token.IDENT, Literal: "add"},
+				{Type: token.ASSIGN, Literal: "="},
+				{Type: token.FUNCTION, Literal: "fn"},
+				{Type: token.LPAREN, Literal: "("},
+				{Type: token.IDENT, Literal: "x"},
+				{Type: token.COMMA, Literal: ","},
+				{Type: token.IDENT, Literal: "y"},
+				{Type: token.RPAREN, Literal: ")"},
+				{Type: token.LBRACE, Literal: "{"},
+				{Type: token.IDENT, Literal: "x"},
+				{Type: token.PLUS, Literal: "+"},
+				{Type: token.IDENT, Literal: "y"},
+				{Type: token.SEMICOLON, Literal: ""},
+				{Type: token.RBRACE, Literal: "}"},
+				{Type: token.SEMICOLON, Literal: ""},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			"if (true) { return 1; }",
+			[]token.Token{
+				{Type: token.IF, Literal: "if"},
+				{Type: token.LPAREN, Literal: "("},
+				{Type: token.TRUE, Literal: "true"},
+				{Type: token.RPAREN, Literal: ")"},
+				{Type: token.LBRACE, Literal: "{"},
+				{Type: token.RETURN, Literal: "return"},
+				{Type: token.INT, Literal: "1"},
+				{Type: token.SEMICOLON, Literal: ";"},
+				{Type: token.RBRACE, Literal: "}"},
+				{Type: token.SEMICOLON, Literal: ""},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			"{ }",
+			[]token.Token{
+				{Type: token.LBRACE, Literal: "{"},
+				{Type: token.RBRACE, Literal: "}"},
+				{Type: token.SEMICOLON, Literal: ""},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			"x +",
+			[]token.Token{
+				{Type: token.IDENT, Literal: "x"},
+				{Type: token.PLUS, Literal: "+"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			"",
+			[]token.Token{
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		token.AssertTokens(t, Tokenize(tt.input, WithSemicolonInsertion()), tt.expected)
+	}
+}
+
+func TestSemicolonInsertionPositions(t *testing.T) {
+	l := New("x\n}", WithSemicolonInsertion())
+
+	expected := []struct {
+		expectedType token.TokenType
+		expectedPos  string
+		expectedEnd  string
+	}{
+		{token.IDENT, "1:1", "1:2"},
+		{token.SEMICOLON, "1:2", "1:2"},
+		{token.RBRACE, "2:1", "2:2"},
+		{token.SEMICOLON, "2:2", "2:2"},
+		{token.EOF, "2:2", "2:2"},
+		{token.EOF, "2:2", "2:2"},
+	}
+
+	for i, tt := range expected {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos.String() != tt.expectedPos || tok.End.String() != tt.expectedEnd {
+			t.Fatalf("tests[%d] - span wrong. expected %s-%s, got %s-%s", i, tt.expectedPos, tt.expectedEnd, tok.Pos, tok.End)
+		}
+	}
+}
+
+func TestSemicolonInsertionTemplates(t *testing.T) {
+	tokens := Tokenize(`"${ {a} }"`, WithSemicolonInsertion())
+
+	if len(tokens) != 3 || tokens[0].Type != token.TEMPLATE || tokens[1].Type != token.SEMICOLON {
+		t.Fatalf("expected TEMPLATE, SEMICOLON and EOF, got %+v", tokens)
+	}
+	for _, tok := range tokens[0].Parts[0].Expr {
+		if tok.Type == token.SEMICOLON {
+			t.Fatalf("expected no semicolon inside the template expression, got %+v", tokens[0].Parts[0].Expr)
+		}
+	}
+}
+
+func TestSemicolonInsertionDisallowed(t *testing.T) {
+	expected := []token.Token{
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.ERROR, Literal: `";" (;) is not allowed`},
+		{Type: token.IDENT, Literal: "y"},
+		{Type: token.SEMICOLON, Literal: ""},
+		{Type: token.EOF, Literal: ""},
+	}
+	token.AssertTokens(t, Tokenize("x; y", WithSemicolonInsertion(), WithDisallowed(token.SEMICOLON)), expected)
+}
+
+func TestIntegerDivision(t *testing.T) {
+	input := "a // b # x\n// y"
+
+	enabled := []token.Token{
+		{Type: token.IDENT, Literal: "a"},
+		{Type: token.INT_DIV, Literal: "//"},
+		{Type: token.IDENT, Literal: "b"},
+		{Type: token.INT_DIV, Literal: "//"},
+		{Type: token.IDENT, Literal: "y"},
+		{Type: token.EOF, Literal: ""},
+	}
+	token.AssertTokens(t, Tokenize(input, WithIntegerDivision(), WithHashComments()), enabled)
+
+	disabled := []token.Token{
+		{Type: token.IDENT, Literal: "a"},
+		{Type: token.EOF, Literal: ""},
+	}
+	token.AssertTokens(t, Tokenize(input), disabled)
+
+	slashes := []token.Token{
+		{Type: token.INT_DIV, Literal: "//"},
+		{Type: token.SLASH, Literal: "/"},
+		{Type: token.SLASH, Literal: "/"},
+		{Type: token.EOF, Literal: ""},
+	}
+	token.AssertTokens(t, Tokenize("/// /", WithIntegerDivision()), slashes)
+}
+
+func TestHeredocs(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{"<<<END\nfirst \\n line\n\n  \"second\" line\nEND", token.STRING, "first \\n line\n\n  \"second\" line"},
+		{"<<<END\n  indented\n  END\n", token.STRING, "  indented"},
+		{"<<<END\nline\nEND \t\n", token.STRING, "line"},
+		{"<<<END\nEND", token.STRING, ""},
+		{"<<<END\r\nline\r\nEND\r\n", token.STRING, "line"},
+		{"<<<EOT\nEND\nEOT", token.STRING, "END"},
+		{"<<<END\nline\nENDING\n", token.ERROR, "unterminated heredoc"},
+		{"<<<END\nline", token.ERROR, "unterminated heredoc"},
+		{"<<<END rest\nEND", token.ERROR, `heredoc delimiter "END" must end the line`},
+		{"<<<\nEND", token.ERROR, "missing heredoc delimiter"},
+		{"<<<1A\nx\n1A", token.ERROR, "missing heredoc delimiter"},
+		{"<< b", token.ILLEGAL, "<<"},
+		{"< <", token.LT, "<"},
+	}
+
+	for i, tt := range tests {
+		tok := New(tt.input).NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestHeredocPositions(t *testing.T) {
+	l := New("let s = <<<END\na\nEND\ns")
+
+	expected := []struct {
+		expectedType token.TokenType
+		expectedPos  string
+	}{
+		{token.LET, "1:1"},
+		{token.IDENT, "1:5"},
+		{token.ASSIGN, "1:7"},
+		{token.STRING, "1:9"},
+		{token.IDENT, "4:1"},
+		{token.EOF, "4:2"},
+	}
+
+	for i, tt := range expected {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos.String() != tt.expectedPos {
+			t.Fatalf("tests[%d] - position wrong. expected %s, got %s", i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestCaseInsensitiveKeywords(t *testing.T) {
+	input := "LET Let let Fn TRUE x X"
+
+	enabled := []token.Token{
+		{Type: token.LET, Literal: "LET"},
+		{Type: token.LET, Literal: "Let"},
+		{Type: token.LET, Literal: "let"},
+		{Type: token.FUNCTION, Literal: "Fn"},
+		{Type: token.TRUE, Literal: "TRUE"},
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.IDENT, Literal: "X"},
+		{Type: token.EOF, Literal: ""},
+	}
+	token.AssertTokens(t, Tokenize(input, WithCaseInsensitiveKeywords()), enabled)
+
+	disabled := []token.Token{
+		{Type: token.IDENT, Literal: "LET"},
+		{Type: token.IDENT, Literal: "Let"},
+		{Type: token.LET, Literal: "let"},
+		{Type: token.IDENT, Literal: "Fn"},
+		{Type: token.IDENT, Literal: "TRUE"},
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.IDENT, Literal: "X"},
+		{Type: token.EOF, Literal: ""},
+	}
+	token.AssertTokens(t, Tokenize(input), disabled)
+}
+
+func TestMarkRestore(t *testing.T) {
+	input := "let x = 5;\nlet add = fn(a, b) { a + b }\nadd(x, 1)"
+
+	tests := []struct {
+		name string
+		fn   func(l *Lexer)
+		opts []Option
+	}{
+		{"consumed", func(l *Lexer) { l.NextToken() }, nil},
+		{"peeked", func(l *Lexer) { l.PeekN(3) }, nil},
+		{"pending", func(l *Lexer) {
+			// Consume up to the semicolon inserted before '}'.
+			for i := 0; i < 4; i++ {
+				l.NextToken()
+			}
+		}, []Option{WithSemicolonInsertion()}},
+	}
+
+	for _, tt := range tests {
+		l := New(input, tt.opts...)
+		for i := 0; i < 14; i++ {
+			l.NextToken()
+		}
+		tt.fn(l)
+
+		mark := l.Mark()
+		first := drain(l)
+		l.Restore(mark)
+		l.PeekN(2)
+		second := drain(l)
+		l.Restore(mark)
+		third := drain(l)
+
+		for _, again := range [][]token.Token{second, third} {
+			if len(again) != len(first) {
+				t.Fatalf("%s - token count wrong after Restore. expected %d, got %d", tt.name, len(first), len(again))
+			}
+			for i := range first {
+				if again[i].Type != first[i].Type || again[i].Literal != first[i].Literal ||
+					again[i].Pos != first[i].Pos || again[i].End != first[i].End {
+					t.Fatalf("%s - tokens[%d] wrong after Restore. expected %+v, got %+v", tt.name, i, first[i], again[i])
+				}
+			}
+		}
+	}
+}
+
+// drain consumes the remaining tokens of l, up to and including EOF.
+func drain(l *Lexer) []token.Token {
+	var tokens []token.Token
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
+}
+
+func TestIdentifierDigits(t *testing.T) {
+	input := "abc123 _9 foo2bar 1abc x1+2"
+
+	expected := []token.Token{
+		{Type: token.IDENT, Literal: "abc123"},
+		{Type: token.IDENT, Literal: "_9"},
+		{Type: token.IDENT, Literal: "foo2bar"},
+		{Type: token.INT, Literal: "1"},
+		{Type: token.IDENT, Literal: "abc"},
+		{Type: token.IDENT, Literal: "x1"},
+		{Type: token.PLUS, Literal: "+"},
+		{Type: token.INT, Literal: "2"},
+		{Type: token.EOF, Literal: ""},
+	}
+	token.AssertTokens(t, Tokenize(input), expected)
+}
+
+func TestLimits(t *testing.T) {
+	limits := Limits{Identifier: 4, String: 3, Number: 2}
+
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{"abcd", token.IDENT, "abcd"},
+		{"abcde", token.ERROR, "identifier longer than 4 bytes"},
+		{"return", token.RETURN, "return"},
+		{`"abc"`, token.STRING, "abc"},
+		{`"abcd"`, token.ERROR, "string longer than 3 bytes"},
+		{`"\n\n\n"`, token.STRING, "\n\n\n"},
+		{`"${xy}"`, token.ERROR, "string longer than 3 bytes"},
+		{"<<<END\nabcd\nEND", token.ERROR, "string longer than 3 bytes"},
+		{"12", token.INT, "12"},
+		{"123", token.ERROR, "number longer than 2 bytes"},
+	}
+
+	for i, tt := range tests {
+		tok := New(tt.input, WithLimits(limits)).NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	token.AssertTokens(t, Tokenize("abcde 123"), []token.Token{
+		{Type: token.IDENT, Literal: "abcde"},
+		{Type: token.INT, Literal: "123"},
+		{Type: token.EOF, Literal: ""},
+	})
+}
@@ -0,0 +1,101 @@
+package lexer
+
+import "github.com/magalhaesm/monkey-lang/token"
+
+// operators maps the operators and delimiters recognized by the lexer to their
+// token types. Adding an operator only requires adding it here.
+var operators = map[string]token.TokenType{
+	"=":   token.ASSIGN,
+	"+":   token.PLUS,
+	"-":   token.MINUS,
+	"!":   token.BANG,
+	"*":   token.ASTERISK,
+	"/":   token.SLASH,
+	"?":   token.QUESTION,
+	"...": token.ELLIPSIS,
+	"<":   token.LT,
+	">":   token.GT,
+	"==":  token.EQ,
+	"!=":  token.NOT_EQ,
+	",":   token.COMMA,
+	";":   token.SEMICOLON,
+	":":   token.COLON,
+	"(":   token.LPAREN,
+	")":   token.RPAREN,
+	"{":   token.LBRACE,
+	"}":   token.RBRACE,
+}
+
+// heredocStart is the type given in the trie to "<<<", which is not an operator
+// but opens a heredoc.
+const heredocStart token.TokenType = "<<<"
+
+// operatorTrie is the trie built from the operators table and the heredoc opener.
+var operatorTrie = newTrie(withOperator(operators, "<<<", heredocStart))
+
+// intDivTrie is operatorTrie extended with "//", used with WithIntegerDivision.
+var intDivTrie = newTrie(withOperator(withOperator(operators, "<<<", heredocStart), "//", token.INT_DIV))
+
+// withOperator returns a copy of the operators table with op added to it.
+func withOperator(ops map[string]token.TokenType, op string, tokenType token.TokenType) map[string]token.TokenType {
+	extended := make(map[string]token.TokenType, len(ops)+1)
+	for k, v := range ops {
+		extended[k] = v
+	}
+	extended[op] = tokenType
+	return extended
+}
+
+// trieNode is a node in a trie of operators, where each edge is a character.
+type trieNode struct {
+	children  map[byte]*trieNode
+	tokenType token.TokenType // Type of the operator spelled by the path to this node, if any
+}
+
+// newTrie builds a trie from a table of operators and their token types.
+func newTrie(ops map[string]token.TokenType) *trieNode {
+	root := &trieNode{children: make(map[byte]*trieNode)}
+	for op, tokenType := range ops {
+		node := root
+		for i := 0; i < len(op); i++ {
+			child, ok := node.children[op[i]]
+			if !ok {
+				child = &trieNode{children: make(map[byte]*trieNode)}
+				node.children[op[i]] = child
+			}
+			node = child
+		}
+		node.tokenType = tokenType
+	}
+	return root
+}
+
+// readOperator reads the operator starting at the current character, reporting false
+// if no operator starts with it. It follows the trie for as long as the next character
+// extends the match, so the longest operator wins (e.g. "==" over "="). If the match
+// stops at a prefix that is not an operator itself, like ".." for "..." or "<<" for
+// the heredoc opener "<<<", the prefix is returned as an ILLEGAL token. Since no
+// backtracking happens, the lexer never looks more than one character past the end
+// of a token.
+func (l *Lexer) readOperator() (token.Token, bool) {
+	node, ok := l.operators.children[l.ch]
+	if !ok {
+		return token.Token{}, false
+	}
+
+	position := l.position
+	for {
+		next, ok := node.children[l.peekChar()]
+		if !ok {
+			break
+		}
+		l.readChar()
+		node = next
+	}
+
+	literal := l.input[position : l.position+1]
+	if node.tokenType == "" {
+		return token.Token{Type: token.ILLEGAL, Literal: literal}, true
+	}
+	return token.Token{Type: node.tokenType, Literal: literal}, true
+}
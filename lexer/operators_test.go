@@ -0,0 +1,79 @@
+package lexer
+
+import (
+	"testing"
+
+	"github.com/magalhaesm/monkey-lang/token"
+)
+
+func TestOperatorLongestMatch(t *testing.T) {
+	const (
+		LT_EQ      = "<="
+		SHL        = "<<"
+		SHL_ASSIGN = "<<="
+	)
+
+	ops := map[string]token.TokenType{
+		"<":   token.LT,
+		"<=":  LT_EQ,
+		"<<":  SHL,
+		"<<=": SHL_ASSIGN,
+		"=":   token.ASSIGN,
+		"==":  token.EQ,
+	}
+
+	input := `< <= << <<= <<<= <=< === x<<=1`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LT, "<"},
+		{LT_EQ, "<="},
+		{SHL, "<<"},
+		{SHL_ASSIGN, "<<="},
+		{SHL, "<<"},
+		{LT_EQ, "<="},
+		{LT_EQ, "<="},
+		{token.LT, "<"},
+		{token.EQ, "=="},
+		{token.ASSIGN, "="},
+		{token.IDENT, "x"},
+		{SHL_ASSIGN, "<<="},
+		{token.INT, "1"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	l.operators = newTrie(ops)
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestOperatorTable(t *testing.T) {
+	for op, tokenType := range operators {
+		l := New(op)
+		tok := l.NextToken()
+
+		if tok.Type != tokenType {
+			t.Errorf("operator %q - tokentype wrong. expected %q, got %q", op, tokenType, tok.Type)
+		}
+
+		if tok.Literal != op {
+			t.Errorf("operator %q - literal wrong. expected %q, got %q", op, op, tok.Literal)
+		}
+
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Errorf("operator %q - expected EOF, got %q", op, tok.Type)
+		}
+	}
+}
@@ -1,20 +1,28 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/user"
+	"path/filepath"
 
 	"github.com/magalhaesm/monkey-lang/repl"
 )
 
 func main() {
+	noHistory := flag.Bool("no-history", false, "do not record the input history or save it to ~/.monkey_history")
+	flag.Parse()
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
-	fmt.Printf("Hello, %s! This is the Monkey programming language!\n",
-		user.Username)
-	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	banner := fmt.Sprintf("Hello, %s! This is the Monkey programming language!\n"+
+		"Feel free to type in commands\n", user.Username)
+	repl.Start(os.Stdin, os.Stdout, repl.Config{
+		Banner:      banner,
+		HistoryFile: filepath.Join(user.HomeDir, ".monkey_history"),
+		NoHistory:   *noHistory,
+	})
 }
@@ -0,0 +1,110 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// Control characters and escape sequences understood by the line editor.
+const (
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyBackspace = 8
+	keyEnter     = '\r'
+	keyNewline   = '\n'
+	keyEscape    = 27
+	keyDelete    = 127
+
+	arrowUp   = 'A'
+	arrowDown = 'B'
+)
+
+// lineEditor reads lines from a terminal in raw mode, echoing the input itself.
+// It supports backspace and recalling history entries with the up and down arrow keys.
+type lineEditor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	history *History
+}
+
+// readLine shows the prompt and reads a line. It reports false at the end of the
+// input, or when Ctrl-D is pressed on an empty line. Ctrl-C discards the line being
+// typed and shows the prompt again.
+func (e *lineEditor) readLine(prompt string) (string, bool) {
+	var buf []byte
+	redraw := func() {
+		fmt.Fprintf(e.out, "\r\x1b[K%s%s", prompt, buf)
+	}
+
+	fmt.Fprint(e.out, prompt)
+	for {
+		ch, err := e.in.ReadByte()
+		if err != nil {
+			return "", false
+		}
+
+		switch ch {
+		case keyEnter, keyNewline:
+			fmt.Fprint(e.out, "\r\n")
+			return string(buf), true
+		case keyCtrlC:
+			buf = buf[:0]
+			fmt.Fprintf(e.out, "^C\r\n%s", prompt)
+		case keyCtrlD:
+			if len(buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", false
+			}
+		case keyBackspace, keyDelete:
+			_, size := utf8.DecodeLastRune(buf)
+			buf = buf[:len(buf)-size]
+			redraw()
+		case keyEscape:
+			if line, ok := e.readArrow(); ok {
+				buf = []byte(line)
+				redraw()
+			}
+		default:
+			if ch >= ' ' {
+				buf = append(buf, ch)
+				e.out.Write([]byte{ch})
+			}
+		}
+	}
+}
+
+// readArrow reads the rest of an escape sequence, up to its final byte, so that
+// unknown sequences like Delete ("\x1b[3~") are discarded whole. For the up and
+// down arrows it returns the history line they recall, reporting false for any
+// other sequence.
+func (e *lineEditor) readArrow() (string, bool) {
+	if ch, err := e.in.ReadByte(); err != nil || ch != '[' {
+		return "", false
+	}
+
+	var seq []byte
+	for {
+		ch, err := e.in.ReadByte()
+		if err != nil {
+			return "", false
+		}
+		seq = append(seq, ch)
+		if 0x40 <= ch && ch <= 0x7e {
+			break
+		}
+	}
+	if len(seq) != 1 {
+		return "", false
+	}
+
+	switch seq[0] {
+	case arrowUp:
+		return e.history.Prev()
+	case arrowDown:
+		return e.history.Next()
+	default:
+		return "", false
+	}
+}
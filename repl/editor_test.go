@@ -0,0 +1,40 @@
+package repl
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLineEditor(t *testing.T) {
+	history := NewHistory()
+	history.Add("let x = 5")
+	history.Add("x * 2")
+
+	input := "ab\x7fc\r" + // Backspace
+		"\x1b[A\x1b[A\r" + // Up twice
+		"\x1b[A\x1b[B\r" + // Up then down
+		"zz\x1b[A\x1b[B\x1b[B\r" + // Down past the newest line clears the input
+		"é\x7f!\r" + // Backspace removes a whole multi-byte character
+		"no\x03yes\r" + // Ctrl-C discards the line being typed
+		"a\x1b[3~\x1b[1~\x1b[1;5Cb\r" + // Unknown sequences are discarded whole
+		"\x04" // Ctrl-D on an empty line
+
+	editor := &lineEditor{in: bufio.NewReader(strings.NewReader(input)), out: io.Discard, history: history}
+
+	expected := []string{"ac", "let x = 5", "x * 2", "", "!", "yes", "ab"}
+	for i, want := range expected {
+		line, ok := editor.readLine(PROMPT)
+		if !ok {
+			t.Fatalf("lines[%d] - unexpected end of input", i)
+		}
+		if line != want {
+			t.Fatalf("lines[%d] - line wrong. expected %q, got %q", i, want, line)
+		}
+	}
+
+	if line, ok := editor.readLine(PROMPT); ok {
+		t.Fatalf("expected end of input after Ctrl-D, got %q", line)
+	}
+}
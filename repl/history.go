@@ -0,0 +1,89 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// History stores the lines entered in a REPL session and keeps a cursor for
+// navigating them, the way the up and down arrow keys do in a line editor.
+type History struct {
+	lines  []string
+	cursor int // Index of the line being recalled; len(lines) means none
+}
+
+// NewHistory returns an empty history.
+func NewHistory() *History {
+	return &History{}
+}
+
+// LoadHistory reads a history previously written by Save, one line per entry.
+// A missing file yields an empty history.
+func LoadHistory(path string) (*History, error) {
+	h := NewHistory()
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h.Add(scanner.Text())
+	}
+	return h, scanner.Err()
+}
+
+// Save writes the history to the file at path, one line per entry.
+func (h *History) Save(path string) error {
+	var sb strings.Builder
+	for _, line := range h.lines {
+		sb.WriteString(line + "\n")
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0o600)
+}
+
+// Add appends a line to the history and resets the navigation cursor.
+// Blank lines and repetitions of the most recent line are not recorded.
+func (h *History) Add(line string) {
+	if strings.TrimSpace(line) != "" && (len(h.lines) == 0 || h.lines[len(h.lines)-1] != line) {
+		h.lines = append(h.lines, line)
+	}
+	h.cursor = len(h.lines)
+}
+
+// Lines returns the recorded lines, oldest first.
+func (h *History) Lines() []string {
+	return h.lines
+}
+
+// Prev moves the cursor to the previous (older) line and returns it. It reports
+// false if there is no older line.
+func (h *History) Prev() (string, bool) {
+	if h.cursor == 0 {
+		return "", false
+	}
+	h.cursor--
+	return h.lines[h.cursor], true
+}
+
+// Next moves the cursor to the next (newer) line and returns it. Moving past the
+// newest line returns an empty line, as when starting a fresh input. It reports
+// false if the cursor was already past the newest line.
+func (h *History) Next() (string, bool) {
+	if h.cursor == len(h.lines) {
+		return "", false
+	}
+	h.cursor++
+	if h.cursor == len(h.lines) {
+		return "", true
+	}
+	return h.lines[h.cursor], true
+}
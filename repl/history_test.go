@@ -0,0 +1,84 @@
+package repl
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHistoryAdd(t *testing.T) {
+	h := NewHistory()
+	for _, line := range []string{"let x = 1", "", "x + 1", "x + 1", "   ", "let x = 1"} {
+		h.Add(line)
+	}
+
+	expected := []string{"let x = 1", "x + 1", "let x = 1"}
+	if !reflect.DeepEqual(h.Lines(), expected) {
+		t.Fatalf("history lines wrong. expected %q, got %q", expected, h.Lines())
+	}
+}
+
+func TestHistoryNavigation(t *testing.T) {
+	h := NewHistory()
+	h.Add("first")
+	h.Add("second")
+	h.Add("third")
+
+	steps := []struct {
+		move         func() (string, bool)
+		expectedLine string
+		expectedOk   bool
+	}{
+		{h.Next, "", false},
+		{h.Prev, "third", true},
+		{h.Prev, "second", true},
+		{h.Prev, "first", true},
+		{h.Prev, "", false},
+		{h.Next, "second", true},
+		{h.Next, "third", true},
+		{h.Next, "", true},
+		{h.Next, "", false},
+	}
+
+	for i, step := range steps {
+		line, ok := step.move()
+		if line != step.expectedLine || ok != step.expectedOk {
+			t.Fatalf("steps[%d] - wrong result. expected (%q, %t), got (%q, %t)", i, step.expectedLine, step.expectedOk, line, ok)
+		}
+	}
+
+	h.Prev()
+	h.Add("fourth")
+	if line, _ := h.Prev(); line != "fourth" {
+		t.Fatalf("Add did not reset the cursor. expected %q, got %q", "fourth", line)
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory of a missing file returned error: %v", err)
+	}
+	if len(h.Lines()) != 0 {
+		t.Fatalf("expected empty history, got %q", h.Lines())
+	}
+
+	h.Add("let x = 5")
+	h.Add("x * 2")
+	if err := h.Save(path); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	loaded, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory returned error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Lines(), h.Lines()) {
+		t.Fatalf("loaded history wrong. expected %q, got %q", h.Lines(), loaded.Lines())
+	}
+	if line, _ := loaded.Prev(); line != "x * 2" {
+		t.Fatalf("loaded history cursor wrong. expected %q, got %q", "x * 2", line)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"os"
+	"strings"
+	"unicode"
 
 	"github.com/magalhaesm/monkey-lang/lexer"
 	"github.com/magalhaesm/monkey-lang/token"
@@ -11,21 +14,153 @@ import (
 
 const PROMPT = ">> "
 
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+// Config customizes the appearance of a REPL session. The zero value gives the
+// default behavior.
+type Config struct {
+	Prompt string // Prompt shown before each input; defaults to PROMPT
+	Banner string // Text written once when the session starts
+
+	// ContinuationPrompt, if set, enables multi-line input: while the input has
+	// unclosed parentheses or braces, further lines are read after showing it.
+	ContinuationPrompt string
+
+	// HistoryFile, if set, is the file the history is loaded from when the session
+	// starts and saved to when it ends. If it cannot be loaded, it is left untouched.
+	HistoryFile string
+
+	// NoHistory disables the history: lines are neither recorded for recall with the
+	// arrow keys nor loaded from or saved to HistoryFile.
+	NoHistory bool
+}
+
+// commands maps the names of the REPL meta-commands (lines starting with ':')
+// to their handlers. Each handler receives the rest of the line as its argument.
+var commands = map[string]func(args string, out io.Writer){
+	"tokens": dumpTokens,
+}
+
+func Start(in io.Reader, out io.Writer, config Config) {
+	prompt := config.Prompt
+	if prompt == "" {
+		prompt = PROMPT
+	}
+
+	fmt.Fprint(out, config.Banner)
+
+	history := NewHistory()
+	if config.HistoryFile != "" && !config.NoHistory {
+		loaded, err := LoadHistory(config.HistoryFile)
+		if err != nil {
+			fmt.Fprintf(out, "could not load history: %v\n", err)
+		} else {
+			history = loaded
+			defer func() {
+				if err := history.Save(config.HistoryFile); err != nil {
+					fmt.Fprintf(out, "could not save history: %v\n", err)
+				}
+			}()
+		}
+	}
+
+	readLine := newLineReader(in, out, history)
+	read := func(prompt string) (string, bool) {
+		line, ok := readLine(prompt)
+		if ok && !config.NoHistory {
+			history.Add(line)
+		}
+		return line, ok
+	}
 
 	for {
-		fmt.Printf(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		line, ok := read(prompt)
+		if !ok {
 			return
 		}
 
-		line := scanner.Text()
+		if strings.HasPrefix(line, ":") {
+			runCommand(line[1:], out)
+			continue
+		}
+
+		for config.ContinuationPrompt != "" && isIncomplete(line) {
+			next, ok := read(config.ContinuationPrompt)
+			if !ok {
+				break
+			}
+			line += "\n" + next
+		}
+
 		l := lexer.New(line)
 
 		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
-			fmt.Printf("%+v\n", tok)
+			fmt.Fprintf(out, "{Type:%s Literal:%s}\n", tok.Type, tok.Literal)
 		}
 	}
 }
+
+// newLineReader returns a function that shows a prompt and reads a line of input,
+// reporting false at the end of the input. When in is a terminal, lines are read
+// with a line editor that recalls the history with the arrow keys; otherwise, as
+// for files, pipes and tests, they are read as plain text.
+func newLineReader(in io.Reader, out io.Writer, history *History) func(prompt string) (string, bool) {
+	if f, ok := in.(*os.File); ok && isTerminal(f.Fd()) {
+		editor := &lineEditor{in: bufio.NewReader(f), out: out, history: history}
+		return func(prompt string) (string, bool) {
+			restore, err := makeRaw(f.Fd())
+			if err != nil {
+				return "", false
+			}
+			defer restore()
+			return editor.readLine(prompt)
+		}
+	}
+
+	scanner := bufio.NewScanner(in)
+	return func(prompt string) (string, bool) {
+		fmt.Fprint(out, prompt)
+		if !scanner.Scan() {
+			return "", false
+		}
+		return scanner.Text(), true
+	}
+}
+
+// isIncomplete reports whether the input has unclosed parentheses or braces.
+func isIncomplete(input string) bool {
+	depth := 0
+	l := lexer.New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACE:
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// runCommand executes a meta-command line, with the leading ':' already removed. The
+// command name may be preceded by whitespace and ends at the next whitespace character.
+func runCommand(line string, out io.Writer) {
+	line = strings.TrimLeftFunc(line, unicode.IsSpace)
+	name, args := line, ""
+	if i := strings.IndexFunc(line, unicode.IsSpace); i >= 0 {
+		name, args = line[:i], line[i:]
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(out, "unknown command: :%s\n", name)
+		return
+	}
+	cmd(strings.TrimSpace(args), out)
+}
+
+// dumpTokens prints the position, type and literal of every token in the input.
+func dumpTokens(input string, out io.Writer) {
+	l := lexer.New(input)
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(out, "%s\t%s\t%q\n", tok.Pos, tok.Type, tok.Literal)
+	}
+}
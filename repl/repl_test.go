@@ -0,0 +1,145 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTokensCommand(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			":tokens let x = 5\n",
+			PROMPT +
+				"1:1\tLET\t\"let\"\n" +
+				"1:5\tIDENT\t\"x\"\n" +
+				"1:7\t=\t\"=\"\n" +
+				"1:9\tINT\t\"5\"\n" +
+				PROMPT,
+		},
+		{
+			":tokens\tx\n",
+			PROMPT + "1:1\tIDENT\t\"x\"\n" + PROMPT,
+		},
+		{
+			": tokens x\n",
+			PROMPT + "1:1\tIDENT\t\"x\"\n" + PROMPT,
+		},
+		{
+			":tokens\n",
+			PROMPT + PROMPT,
+		},
+		{
+			":bogus 1\n",
+			PROMPT + "unknown command: :bogus\n" + PROMPT,
+		},
+		{
+			"x + 1\n",
+			PROMPT +
+				"{Type:IDENT Literal:x}\n" +
+				"{Type:+ Literal:+}\n" +
+				"{Type:INT Literal:1}\n" +
+				PROMPT,
+		},
+	}
+
+	for i, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input), &out, Config{})
+
+		if out.String() != tt.expected {
+			t.Errorf("tests[%d] - output wrong.\nexpected:\n%q\ngot:\n%q", i, tt.expected, out.String())
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	tests := []struct {
+		config   Config
+		input    string
+		expected string
+	}{
+		{
+			Config{Prompt: "monkey> ", Banner: "Welcome!\n"},
+			"1\n",
+			"Welcome!\nmonkey> {Type:INT Literal:1}\nmonkey> ",
+		},
+		{
+			Config{ContinuationPrompt: ".. "},
+			"f(x, {\n 1\n})\n",
+			PROMPT + ".. " + ".. " +
+				"{Type:IDENT Literal:f}\n" +
+				"{Type:( Literal:(}\n" +
+				"{Type:IDENT Literal:x}\n" +
+				"{Type:, Literal:,}\n" +
+				"{Type:{ Literal:{}\n" +
+				"{Type:INT Literal:1}\n" +
+				"{Type:} Literal:}}\n" +
+				"{Type:) Literal:)}\n" +
+				PROMPT,
+		},
+		{
+			Config{ContinuationPrompt: ".. "},
+			"\"(\" // {\n",
+			PROMPT + "{Type:STRING Literal:(}\n" + PROMPT,
+		},
+		{
+			Config{},
+			"f(\n",
+			PROMPT + "{Type:IDENT Literal:f}\n{Type:( Literal:(}\n" + PROMPT,
+		},
+	}
+
+	for i, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input), &out, tt.config)
+
+		if out.String() != tt.expected {
+			t.Errorf("tests[%d] - output wrong.\nexpected:\n%q\ngot:\n%q", i, tt.expected, out.String())
+		}
+	}
+}
+
+func TestHistoryConfig(t *testing.T) {
+	tests := []struct {
+		noHistory bool
+		expected  string
+	}{
+		{false, "let x = 5\nx\n:tokens y\n"},
+		{true, "let x = 5\n"},
+	}
+
+	for i, tt := range tests {
+		path := filepath.Join(t.TempDir(), "history")
+		if err := os.WriteFile(path, []byte("let x = 5\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		Start(strings.NewReader("x\n\n:tokens y\n"), &out, Config{HistoryFile: path, NoHistory: tt.noHistory})
+
+		saved, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(saved) != tt.expected {
+			t.Errorf("tests[%d] - saved history wrong. expected %q, got %q", i, tt.expected, saved)
+		}
+	}
+}
+
+func TestNoHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	var out bytes.Buffer
+	Start(strings.NewReader("x\n"), &out, Config{HistoryFile: path, NoHistory: true})
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no history file with NoHistory, got err %v", err)
+	}
+}
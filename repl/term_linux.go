@@ -0,0 +1,45 @@
+//go:build linux
+
+package repl
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// makeRaw puts the terminal referred to by fd into raw mode, so input is read a
+// byte at a time without being echoed, and returns a function restoring the
+// previous mode. Ctrl-C is read as a byte instead of raising SIGINT, which would
+// exit without restoring the mode; output processing is left enabled. It fails
+// if fd is not a terminal.
+func makeRaw(fd uintptr) (restore func(), err error) {
+	var old syscall.Termios
+	if err := ioctlTermios(fd, syscall.TCGETS, &old); err != nil {
+		return nil, err
+	}
+
+	raw := old
+	raw.Iflag &^= syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.IEXTEN | syscall.ISIG
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctlTermios(fd, syscall.TCSETS, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() { ioctlTermios(fd, syscall.TCSETS, &old) }, nil
+}
+
+// isTerminal reports whether fd refers to a terminal.
+func isTerminal(fd uintptr) bool {
+	var t syscall.Termios
+	return ioctlTermios(fd, syscall.TCGETS, &t) == nil
+}
+
+func ioctlTermios(fd, req uintptr, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package repl
+
+import "errors"
+
+// isTerminal always reports false, as raw terminal mode is not supported on this platform.
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+// makeRaw is not supported on this platform, so the REPL falls back to reading
+// plain lines without history recall.
+func makeRaw(fd uintptr) (restore func(), err error) {
+	return nil, errors.New("repl: raw terminal mode is not supported")
+}
@@ -0,0 +1,46 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TB is the part of testing.TB used by AssertTokens, declared here so that the
+// token package does not import testing.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Equal reports whether two tokens have the same type and literal. Positions and
+// attached comments are ignored.
+func (t Token) Equal(other Token) bool {
+	return t.Type == other.Type && t.Literal == other.Literal
+}
+
+// AssertTokens reports a test error listing every index at which got and want hold
+// tokens that are not Equal, including tokens missing from either slice.
+func AssertTokens(t TB, got, want []Token) {
+	t.Helper()
+
+	var diff strings.Builder
+	for i := 0; i < max(len(got), len(want)); i++ {
+		switch {
+		case i >= len(got):
+			fmt.Fprintf(&diff, "\n  [%d] missing, want %s", i, formatToken(want[i]))
+		case i >= len(want):
+			fmt.Fprintf(&diff, "\n  [%d] got %s, want nothing", i, formatToken(got[i]))
+		case !got[i].Equal(want[i]):
+			fmt.Fprintf(&diff, "\n  [%d] got %s, want %s", i, formatToken(got[i]), formatToken(want[i]))
+		}
+	}
+
+	if diff.Len() > 0 {
+		t.Errorf("tokens differ (got %d, want %d):%s", len(got), len(want), diff.String())
+	}
+}
+
+// formatToken formats a token's type and literal for test output.
+func formatToken(t Token) string {
+	return fmt.Sprintf("%s %q", t.Type, t.Literal)
+}
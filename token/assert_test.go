@@ -0,0 +1,79 @@
+package token
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b     Token
+		expected bool
+	}{
+		{Token{Type: IDENT, Literal: "x"}, Token{Type: IDENT, Literal: "x"}, true},
+		{
+			Token{Type: IDENT, Literal: "x", Pos: Position{Offset: 0, Line: 1, Column: 1}},
+			Token{Type: IDENT, Literal: "x", Pos: Position{Offset: 8, Line: 2, Column: 3}},
+			true,
+		},
+		{Token{Type: IDENT, Literal: "x"}, Token{Type: IDENT, Literal: "y"}, false},
+		{Token{Type: INT, Literal: "5"}, Token{Type: STRING, Literal: "5"}, false},
+	}
+
+	for i, tt := range tests {
+		if got := tt.a.Equal(tt.b); got != tt.expected {
+			t.Errorf("tests[%d] - Equal wrong. expected %t, got %t", i, tt.expected, got)
+		}
+	}
+}
+
+// recorder is a TB that records reported errors instead of failing.
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertTokens(t *testing.T) {
+	want := []Token{
+		{Type: LET, Literal: "let"},
+		{Type: IDENT, Literal: "x"},
+		{Type: ASSIGN, Literal: "="},
+		{Type: INT, Literal: "5"},
+	}
+
+	r := &recorder{}
+	AssertTokens(r, want, want)
+	if len(r.errors) != 0 {
+		t.Fatalf("expected no errors for equal slices, got %q", r.errors)
+	}
+
+	got := []Token{
+		{Type: LET, Literal: "let"},
+		{Type: IDENT, Literal: "y"},
+		{Type: ASSIGN, Literal: "="},
+	}
+
+	r = &recorder{}
+	AssertTokens(r, got, want)
+
+	expected := "tokens differ (got 3, want 4):\n" +
+		"  [1] got IDENT \"y\", want IDENT \"x\"\n" +
+		"  [3] missing, want INT \"5\""
+	if len(r.errors) != 1 || r.errors[0] != expected {
+		t.Fatalf("wrong errors.\nexpected: %q\ngot:      %q", expected, r.errors)
+	}
+
+	r = &recorder{}
+	AssertTokens(r, append(want, Token{Type: SEMICOLON, Literal: ";"}), want)
+
+	expected = "tokens differ (got 5, want 4):\n" +
+		"  [4] got ; \";\", want nothing"
+	if len(r.errors) != 1 || r.errors[0] != expected {
+		t.Fatalf("wrong errors.\nexpected: %q\ngot:      %q", expected, r.errors)
+	}
+}
@@ -1,5 +1,7 @@
 package token
 
+import "fmt"
+
 // TokenType represents the type of a token in the language.
 type TokenType string
 
@@ -7,15 +9,50 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // Position of the first character of the token
+	End     Position // Position immediately after the last character of the token
+
+	// Comments holds the comments that precede the token on their own lines.
+	// It is only populated when the lexer is configured to preserve comments.
+	Comments []Token
+	// Trailing holds the comment that follows the token on the same line, if any.
+	// It is only populated when the lexer is configured to preserve comments.
+	Trailing *Token
+
+	// Parts holds the segments of a TEMPLATE token, in source order.
+	Parts []TemplatePart
+}
+
+// TemplatePart is a segment of an interpolated string: either literal text or the
+// tokens of an embedded ${...} expression.
+type TemplatePart struct {
+	Text string  // Literal text, with escape sequences decoded, if Expr is nil
+	Expr []Token // Tokens of an embedded expression, excluding "${" and "}"
+}
+
+// Position represents a location in the source code.
+type Position struct {
+	Offset int // Byte offset, starting at 0
+	Line   int // Line number, starting at 1
+	Column int // Column number in bytes, starting at 1
+}
+
+// String returns the position formatted as "line:column".
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 // Constants for token types.
 const (
 	ILLEGAL = "ILLEGAL"
+	ERROR   = "ERROR" // Rejected input; the literal holds the error message
 	EOF     = "EOF"
 
-	IDENT = "IDENT"
-	INT   = "INT"
+	IDENT    = "IDENT"
+	INT      = "INT"
+	STRING   = "STRING"
+	TEMPLATE = "TEMPLATE" // String with embedded ${...} expressions
+	COMMENT  = "COMMENT"
 
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -23,6 +60,9 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	INT_DIV  = "//" // Only with the lexer's integer division option
+	QUESTION = "?"
+	ELLIPSIS = "..."
 
 	LT = "<"
 	GT = ">"
@@ -32,6 +72,7 @@ const (
 
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
 
 	LPAREN = "("
 	RPAREN = ")"
@@ -40,22 +81,36 @@ const (
 
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	IMPORT   = "IMPORT"
+	DO       = "DO"
+	WHILE    = "WHILE"
+	SWITCH   = "SWITCH"
+	CASE     = "CASE"
+	DEFAULT  = "DEFAULT"
 )
 
 // keywords maps identifiers to their corresponding token types if they are keywords.
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":      FUNCTION,
+	"let":     LET,
+	"const":   CONST,
+	"true":    TRUE,
+	"false":   FALSE,
+	"if":      IF,
+	"else":    ELSE,
+	"return":  RETURN,
+	"import":  IMPORT,
+	"do":      DO,
+	"while":   WHILE,
+	"switch":  SWITCH,
+	"case":    CASE,
+	"default": DEFAULT,
 }
 
 // LookupIdent checks if the identifier is a keyword and returns the appropriate token type.
@@ -65,3 +120,47 @@ func LookupIdent(ident string) TokenType {
 	}
 	return IDENT
 }
+
+// operators is the set of token types that are operators.
+var operators = map[TokenType]bool{
+	ASSIGN:   true,
+	PLUS:     true,
+	MINUS:    true,
+	BANG:     true,
+	ASTERISK: true,
+	SLASH:    true,
+	INT_DIV:  true,
+	QUESTION: true,
+	ELLIPSIS: true,
+	LT:       true,
+	GT:       true,
+	EQ:       true,
+	NOT_EQ:   true,
+}
+
+// literals is the set of token types that are literal values.
+var literals = map[TokenType]bool{
+	INT:      true,
+	STRING:   true,
+	TEMPLATE: true,
+}
+
+// IsOperator reports whether the token type is an operator.
+func IsOperator(t TokenType) bool {
+	return operators[t]
+}
+
+// IsKeyword reports whether the token type is a keyword.
+func IsKeyword(t TokenType) bool {
+	for _, tok := range keywords {
+		if tok == t {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLiteral reports whether the token type is a literal value, such as an integer or string.
+func IsLiteral(t TokenType) bool {
+	return literals[t]
+}
@@ -0,0 +1,58 @@
+package token
+
+import "testing"
+
+func TestClassification(t *testing.T) {
+	tests := []struct {
+		tokenType  TokenType
+		isOperator bool
+		isKeyword  bool
+		isLiteral  bool
+	}{
+		{PLUS, true, false, false},
+		{ASSIGN, true, false, false},
+		{NOT_EQ, true, false, false},
+		{LT, true, false, false},
+		{QUESTION, true, false, false},
+		{ELLIPSIS, true, false, false},
+		{INT_DIV, true, false, false},
+		{LET, false, true, false},
+		{CONST, false, true, false},
+		{FUNCTION, false, true, false},
+		{TRUE, false, true, false},
+		{RETURN, false, true, false},
+		{INT, false, false, true},
+		{STRING, false, false, true},
+		{IDENT, false, false, false},
+		{COMMA, false, false, false},
+		{COLON, false, false, false},
+		{LBRACE, false, false, false},
+		{COMMENT, false, false, false},
+		{EOF, false, false, false},
+	}
+
+	for i, tt := range tests {
+		if got := IsOperator(tt.tokenType); got != tt.isOperator {
+			t.Errorf("tests[%d] - IsOperator(%q) wrong. expected %t, got %t", i, tt.tokenType, tt.isOperator, got)
+		}
+
+		if got := IsKeyword(tt.tokenType); got != tt.isKeyword {
+			t.Errorf("tests[%d] - IsKeyword(%q) wrong. expected %t, got %t", i, tt.tokenType, tt.isKeyword, got)
+		}
+
+		if got := IsLiteral(tt.tokenType); got != tt.isLiteral {
+			t.Errorf("tests[%d] - IsLiteral(%q) wrong. expected %t, got %t", i, tt.tokenType, tt.isLiteral, got)
+		}
+	}
+}
+
+func TestKeywordsAreClassified(t *testing.T) {
+	for ident, tok := range keywords {
+		if !IsKeyword(tok) {
+			t.Errorf("keyword %q (%s) not classified as keyword", ident, tok)
+		}
+		if IsOperator(tok) || IsLiteral(tok) {
+			t.Errorf("keyword %q (%s) classified as operator or literal", ident, tok)
+		}
+	}
+}